@@ -0,0 +1,150 @@
+package rgroup
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// chains holds the dispatch of a HandlerGroup with its handlers, middleware, pre-middleware
+// and hooks, compiled once when the group is built.
+type chains struct {
+	// generation of the HandlerGroup the chains were compiled from
+	gen      uint64
+	handlers HandlerMap
+	versions map[string]HandlerMap
+	// serve dispatches a request through the hooks, pre-middleware and the compiled handlers
+	serve Handler
+	// logger runs the log hooks before the logger, nil for the global logger
+	logger func(*LoggerData)
+}
+
+// compile wraps every handler of the HandlerGroup with the middleware of the group and
+// the enclosing muxes, without the middleware skipped for its method, and the dispatch
+// with the pre-middleware and hooks.
+func (h *HandlerGroup) compile(ctx buildContext, options Options, logger func(*LoggerData)) *chains {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	c := &chains{
+		gen:      atomic.LoadUint64(&h.gen),
+		handlers: make(HandlerMap, len(h.handlers)),
+	}
+
+	for method, f := range h.handlers {
+		c.handlers[method] = h.chain(ctx, method, f)
+	}
+
+	if len(h.versions) > 0 {
+		c.versions = make(map[string]HandlerMap, len(h.versions))
+	}
+
+	for v, handlers := range h.versions {
+		c.versions[v] = make(HandlerMap, len(handlers))
+		for method, f := range handlers {
+			c.versions[v][method] = h.chain(ctx, method, f)
+		}
+	}
+
+	hooks := h.enabledHooks(ctx)
+	c.serve = wrapHooks(hooks, c.dispatch(options).applyMiddleware(h.pre))
+
+	if logger == nil {
+		logger = options.logger
+	}
+	c.logger = logHooks(hooks, logger)
+
+	return c
+}
+
+// chain wraps f with the middleware not skipped for method.
+func (h *HandlerGroup) chain(ctx buildContext, method string, f Handler) Handler {
+	if len(h.skip) == 0 {
+		return f.applyMiddleware(h.middleware).applyMiddleware(ctx.middleware)
+	}
+
+	skip := h.skipped(method)
+
+	return f.applyMiddleware(skipMiddleware(h.middleware, h.tags, skip)).
+		applyMiddleware(skipMiddleware(ctx.middleware, ctx.tags, skip))
+}
+
+// dispatch returns the Handler applying the method override and version selection
+// before calling the compiled handler for the request.
+func (c *chains) dispatch(options Options) Handler {
+	return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		l := groupLog(req)
+
+		req, err := overrideMethod(req, options.methodOverride)
+		l.Method = req.Method
+		if err != nil {
+			return nil, err
+		}
+
+		var requested bool
+		l.Version, requested = options.versioning.resolve(req)
+		options.versioning.setHeaders(w, l.Version)
+
+		f, ok := c.handler(l.Version, req.Method)
+		// versioned handlers take precedence, then the handlers without version;
+		// only versions requested by the client are rejected when unsupported
+		switch {
+		case requested && !c.supports(l.Version):
+			return nil, Error(http.StatusBadRequest).
+				WithResponse("unsupported API version %s", l.Version).
+				WithMessage("unsupported API version %s", l.Version)
+		case ok:
+			return f(w, req)
+		case req.Method == http.MethodOptions:
+			return Response(nil).WithHeader("Allow", strings.Join(c.methodsAllowed(l.Version), ",")), nil
+		default:
+			return nil, Error(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// handler returns the compiled handler for method, preferring the handler of version.
+func (c *chains) handler(version string, method string) (Handler, bool) {
+	if f, ok := c.versions[version][method]; ok {
+		return f, true
+	}
+
+	f, ok := c.handlers[method]
+
+	return f, ok
+}
+
+// supports reports whether the chains can serve version.
+func (c *chains) supports(version string) bool {
+	if version == "" || len(c.versions) == 0 {
+		return true
+	}
+
+	_, ok := c.versions[version]
+
+	return ok
+}
+
+// methodsAllowed returns the http verbs handled for version.
+func (c *chains) methodsAllowed(version string) []string {
+	opts := make([]string, 1, len(c.handlers)+len(c.versions[version])+1)
+	opts[0] = http.MethodOptions
+
+	for k := range c.handlers {
+		opts = append(opts, k)
+	}
+
+	for k := range c.versions[version] {
+		if _, ok := c.handlers[k]; !ok {
+			opts = append(opts, k)
+		}
+	}
+
+	return opts
+}
+
+// changed marks the compiled chains of the HandlerGroup as outdated.
+// It must be called with the registration lock of the group held.
+func (h *HandlerGroup) changed() {
+	atomic.AddUint64(&h.gen, 1)
+}
@@ -3,6 +3,7 @@ package rgroup
 import (
 	"net/http"
 	"sync"
+	"sync/atomic"
 )
 
 type globalConfig struct {
@@ -12,29 +13,128 @@ type globalConfig struct {
 	prewriter       func(*http.Request, *HandlerResponse) *HandlerResponse
 	forwardErrorLog bool
 	lockOnMake      bool
+	strict          bool
 }
 
 type envelopeOptions struct {
 	enabled           bool
 	forwardHTTPStatus bool
 	forwardLogMessage bool
+	builder           EnvelopeBuilder
+	meta              MetaField
+	apiVersion        string
+	requestIDHeader   string
+}
+
+// envelopeOverride holds the envelope options set on a HandlerGroup or HandlerMux.
+// Nil values fall back to the parent HandlerMux and finally to Config.Envelope.
+type envelopeOverride struct {
+	enabled           *bool
+	forwardHTTPStatus *bool
+	forwardLogMessage *bool
+	builder           EnvelopeBuilder
+}
+
+// inherit fills the unset values of o from parent.
+func (o envelopeOverride) inherit(parent envelopeOverride) envelopeOverride {
+	if o.enabled == nil {
+		o.enabled = parent.enabled
+	}
+
+	if o.forwardHTTPStatus == nil {
+		o.forwardHTTPStatus = parent.forwardHTTPStatus
+	}
+
+	if o.forwardLogMessage == nil {
+		o.forwardLogMessage = parent.forwardLogMessage
+	}
+
+	if o.builder == nil {
+		o.builder = parent.builder
+	}
+
+	return o
+}
+
+// apply returns a copy of the global config with the overrides applied.
+func (o envelopeOverride) apply(c globalConfig) *globalConfig {
+	if o.enabled != nil {
+		c.Envelope.enabled = *o.enabled
+	}
+
+	if o.forwardHTTPStatus != nil {
+		c.Envelope.forwardHTTPStatus = *o.forwardHTTPStatus
+	}
+
+	if o.forwardLogMessage != nil {
+		c.Envelope.forwardLogMessage = *o.forwardLogMessage
+	}
+
+	if o.builder != nil {
+		c.Envelope.builder = o.builder
+	}
+
+	return &c
 }
 
 var mtx = sync.Mutex{}
 
+// snapshot holds an immutable copy of Config read on the request path.
+var snapshot atomic.Value
+
+type changeHook struct {
+	id int
+	f  func()
+}
+
+var (
+	changeHooks  []changeHook
+	changeHookID int
+)
+
+// commit publishes a new snapshot of Config, releases mtx and calls the change hooks.
+// Must be called with mtx held.
+func commit() {
+	c := Config
+	snapshot.Store(&c)
+
+	hooks := changeHooks
+	mtx.Unlock()
+
+	for _, h := range hooks {
+		h.f()
+	}
+}
+
+// loadConfig returns the current snapshot of Config.
+// The returned value must not be modified.
+func loadConfig() *globalConfig {
+	return snapshot.Load().(*globalConfig)
+}
+
+// lockedConfig returns a copy of Config read under mtx.
+// Used when building handlers, where changes made directly to Config must be respected.
+func lockedConfig() globalConfig {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	return Config
+}
+
 var defaultConfig = globalConfig{
 	logOptions:      true,
-	Envelope:        envelopeOptions{},
+	Envelope:        envelopeOptions{builder: defaultEnvelopeBuilder{}, requestIDHeader: "X-Request-ID"},
 	logger:          defaultLogger,
 	prewriter:       nil,
 	forwardErrorLog: false,
 	lockOnMake:      true,
+	strict:          false,
 }
 
 // Enable envelope response. Disabled by default
 func (e *envelopeOptions) Enable() {
 	mtx.Lock()
-	defer mtx.Unlock()
+	defer commit()
 
 	e.enabled = true
 }
@@ -42,7 +142,7 @@ func (e *envelopeOptions) Enable() {
 // Disable envelope response. Disabled by default
 func (e *envelopeOptions) Disable() {
 	mtx.Lock()
-	defer mtx.Unlock()
+	defer commit()
 
 	e.enabled = false
 }
@@ -51,7 +151,7 @@ func (e *envelopeOptions) Disable() {
 // Default: false
 func (e *envelopeOptions) SetForwardLogMessage(b bool) {
 	mtx.Lock()
-	defer mtx.Unlock()
+	defer commit()
 
 	e.forwardLogMessage = b
 }
@@ -60,20 +160,90 @@ func (e *envelopeOptions) SetForwardLogMessage(b bool) {
 // Default: false
 func (e *envelopeOptions) SetForwardHTTPStatus(b bool) {
 	mtx.Lock()
-	defer mtx.Unlock()
+	defer commit()
 
 	e.forwardHTTPStatus = b
 
 }
 
+// Set the builder used to create enveloped responses.
+// Passing nil restores the default Envelope structure.
+func (e *envelopeOptions) SetBuilder(b EnvelopeBuilder) {
+	mtx.Lock()
+	defer commit()
+
+	if b == nil {
+		b = defaultEnvelopeBuilder{}
+	}
+
+	e.builder = b
+}
+
+// Set the meta entries populated by rgroup on enveloped responses.
+// Default: none
+func (e *envelopeOptions) SetMeta(fields MetaField) {
+	mtx.Lock()
+	defer commit()
+
+	e.meta = fields
+}
+
+// Set the API version reported with MetaAPIVersion.
+func (e *envelopeOptions) SetAPIVersion(version string) {
+	mtx.Lock()
+	defer commit()
+
+	e.apiVersion = version
+}
+
+// Set the request header the request ID is read from for MetaRequestID.
+// Default: X-Request-ID
+func (e *envelopeOptions) SetRequestIDHeader(header string) {
+	mtx.Lock()
+	defer commit()
+
+	e.requestIDHeader = header
+}
+
 // Config holds the global configuration for the package.
 // All global configurations are set by calling methods on Config.
 var Config globalConfig = defaultConfig
 
+func init() {
+	c := Config
+	snapshot.Store(&c)
+}
+
+// Register a function called after every change to the global config.
+// Config is read through immutable snapshots while serving requests,
+// so it can be safely changed at runtime.
+// The returned function removes the hook.
+func (c *globalConfig) OnChange(f func()) func() {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	changeHookID++
+	id := changeHookID
+	changeHooks = append(changeHooks[:len(changeHooks):len(changeHooks)], changeHook{id: id, f: f})
+
+	return func() {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		hooks := make([]changeHook, 0, len(changeHooks))
+		for _, h := range changeHooks {
+			if h.id != id {
+				hooks = append(hooks, h)
+			}
+		}
+		changeHooks = hooks
+	}
+}
+
 // Reset the global config to the default values.
 func (c *globalConfig) Reset() {
 	mtx.Lock()
-	defer mtx.Unlock()
+	defer commit()
 
 	*c = defaultConfig
 }
@@ -81,7 +251,7 @@ func (c *globalConfig) Reset() {
 // Set the global logger function.
 func (c *globalConfig) SetGlobalLogger(p func(*LoggerData)) {
 	mtx.Lock()
-	defer mtx.Unlock()
+	defer commit()
 
 	if p == nil {
 		p = func(l *LoggerData) {}
@@ -94,7 +264,7 @@ func (c *globalConfig) SetGlobalLogger(p func(*LoggerData)) {
 // Default: true
 func (c *globalConfig) SetLogOptionsRequests(b bool) {
 	mtx.Lock()
-	defer mtx.Unlock()
+	defer commit()
 
 	c.logOptions = b
 }
@@ -103,7 +273,7 @@ func (c *globalConfig) SetLogOptionsRequests(b bool) {
 // This can be used to further process the response before writing to the client.
 func (c *globalConfig) SetPrewriter(f func(*http.Request, *HandlerResponse) *HandlerResponse) {
 	mtx.Lock()
-	defer mtx.Unlock()
+	defer commit()
 
 	c.prewriter = f
 }
@@ -115,18 +285,27 @@ var lockOnMakeOnce sync.Once
 // Default: true
 func (c *globalConfig) LockOnMake(b bool) {
 	mtx.Lock()
-	defer mtx.Unlock()
+	defer commit()
 
 	lockOnMakeOnce.Do(func() {
 		c.lockOnMake = b
 	})
 }
 
+// Panic on registration errors instead of recording them for Validate.
+// Default: false
+func (c *globalConfig) SetStrict(b bool) {
+	mtx.Lock()
+	defer commit()
+
+	c.strict = b
+}
+
 // Send error log message to client.
 // This is only respected if envelope responses are not enabled.
 func (c *globalConfig) SetForwardErrorLog(b bool) {
 	mtx.Lock()
-	defer mtx.Unlock()
+	defer commit()
 
 	c.forwardErrorLog = b
 }
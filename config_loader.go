@@ -0,0 +1,279 @@
+package rgroup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConfigLoader populates the global configuration from a JSON file and environment variables.
+// Load can be called again at any time (e.g. on SIGHUP) to re-apply the configuration.
+//
+// The JSON file has the following structure, all keys are optional:
+//
+//	{
+//	    "log_options_requests": true,
+//	    "forward_error_log": false,
+//	    "envelope": {
+//	        "enabled": true,
+//	        "forward_http_status": false,
+//	        "forward_log_message": false,
+//	        "meta": ["request_id", "server_time", "duration", "api_version"],
+//	        "api_version": "v1",
+//	        "request_id_header": "X-Request-ID"
+//	    }
+//	}
+//
+// Environment variables use the same keys in upper case, joined by underscores
+// and prefixed with EnvPrefix (e.g. RGROUP_ENVELOPE_FORWARD_HTTP_STATUS).
+// Lists are comma separated. Environment variables take precedence over the file.
+type ConfigLoader struct {
+	// Path to the JSON configuration file. Ignored if empty.
+	File string
+	// Prefix of the environment variables. Ignored if empty.
+	EnvPrefix string
+}
+
+// ConfigLoadError is returned by ConfigLoader.Load and lists all unknown keys and invalid values.
+type ConfigLoadError struct {
+	Unknown []string
+	Invalid []error
+}
+
+func (e *ConfigLoadError) Error() string {
+	msgs := make([]string, 0, len(e.Unknown)+len(e.Invalid))
+	for _, k := range e.Unknown {
+		msgs = append(msgs, fmt.Sprintf("unknown key %s", k))
+	}
+
+	for _, err := range e.Invalid {
+		msgs = append(msgs, err.Error())
+	}
+
+	return "[rgroup] invalid configuration: " + strings.Join(msgs, "; ")
+}
+
+type configKey struct {
+	name  string
+	apply func(c *globalConfig, v string) error
+}
+
+var configKeys = []configKey{
+	{name: "log_options_requests", apply: func(c *globalConfig, v string) error {
+		return parseBool(v, &c.logOptions)
+	}},
+	{name: "forward_error_log", apply: func(c *globalConfig, v string) error {
+		return parseBool(v, &c.forwardErrorLog)
+	}},
+	{name: "envelope.enabled", apply: func(c *globalConfig, v string) error {
+		return parseBool(v, &c.Envelope.enabled)
+	}},
+	{name: "envelope.forward_http_status", apply: func(c *globalConfig, v string) error {
+		return parseBool(v, &c.Envelope.forwardHTTPStatus)
+	}},
+	{name: "envelope.forward_log_message", apply: func(c *globalConfig, v string) error {
+		return parseBool(v, &c.Envelope.forwardLogMessage)
+	}},
+	{name: "envelope.meta", apply: func(c *globalConfig, v string) error {
+		m, err := parseMetaFields(v)
+		if err != nil {
+			return err
+		}
+		c.Envelope.meta = m
+		return nil
+	}},
+	{name: "envelope.api_version", apply: func(c *globalConfig, v string) error {
+		c.Envelope.apiVersion = v
+		return nil
+	}},
+	{name: "envelope.request_id_header", apply: func(c *globalConfig, v string) error {
+		if v == "" {
+			return fmt.Errorf("empty value")
+		}
+		c.Envelope.requestIDHeader = v
+		return nil
+	}},
+}
+
+var metaFieldNames = map[string]MetaField{
+	MetaKeyRequestID:  MetaRequestID,
+	MetaKeyServerTime: MetaServerTime,
+	MetaKeyDuration:   MetaDuration,
+	MetaKeyAPIVersion: MetaAPIVersion,
+}
+
+// Load reads the configuration file and environment variables, validates them
+// and applies them to the global config.
+// Keys missing from both sources are reset to their defaults, so reloading after removing
+// a key restores its default. Settings without a key, e.g. the logger or the EnvelopeBuilder,
+// are kept. Nothing is applied if any key is unknown or invalid.
+func (l *ConfigLoader) Load() error {
+	values := make(map[string]string)
+	lerr := new(ConfigLoadError)
+
+	if l.File != "" {
+		if err := l.loadFile(values, lerr); err != nil {
+			return err
+		}
+	}
+
+	if l.EnvPrefix != "" {
+		l.loadEnv(values, lerr)
+	}
+
+	mtx.Lock()
+
+	c := withLoaderDefaults(Config)
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key, ok := findConfigKey(k)
+		if !ok {
+			lerr.Unknown = append(lerr.Unknown, k)
+			continue
+		}
+
+		if err := key.apply(&c, values[k]); err != nil {
+			lerr.Invalid = append(lerr.Invalid, fmt.Errorf("invalid value for %s: %w", k, err))
+		}
+	}
+
+	if len(lerr.Unknown) > 0 || len(lerr.Invalid) > 0 {
+		mtx.Unlock()
+		return lerr
+	}
+
+	Config = c
+	commit()
+
+	return nil
+}
+
+// withLoaderDefaults returns c with the settings configurable by the ConfigLoader reset to their defaults.
+func withLoaderDefaults(c globalConfig) globalConfig {
+	c.logOptions = defaultConfig.logOptions
+	c.forwardErrorLog = defaultConfig.forwardErrorLog
+	c.Envelope.enabled = defaultConfig.Envelope.enabled
+	c.Envelope.forwardHTTPStatus = defaultConfig.Envelope.forwardHTTPStatus
+	c.Envelope.forwardLogMessage = defaultConfig.Envelope.forwardLogMessage
+	c.Envelope.meta = defaultConfig.Envelope.meta
+	c.Envelope.apiVersion = defaultConfig.Envelope.apiVersion
+	c.Envelope.requestIDHeader = defaultConfig.Envelope.requestIDHeader
+
+	return c
+}
+
+func (l *ConfigLoader) loadFile(values map[string]string, lerr *ConfigLoadError) error {
+	b, err := os.ReadFile(l.File)
+	if err != nil {
+		return fmt.Errorf("[rgroup] failed to read configuration file: %w", err)
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return fmt.Errorf("[rgroup] failed to parse configuration file: %w", err)
+	}
+
+	flattenConfig("", m, values, lerr)
+
+	return nil
+}
+
+func flattenConfig(prefix string, m map[string]any, values map[string]string, lerr *ConfigLoadError) {
+	for k, v := range m {
+		name := prefix + k
+
+		switch v := v.(type) {
+		case map[string]any:
+			flattenConfig(name+".", v, values, lerr)
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[name] = strings.Join(items, ",")
+		case nil:
+			lerr.Invalid = append(lerr.Invalid, fmt.Errorf("invalid value for %s: null", name))
+		default:
+			values[name] = fmt.Sprint(v)
+		}
+	}
+}
+
+func (l *ConfigLoader) loadEnv(values map[string]string, lerr *ConfigLoadError) {
+	prefix := strings.ToUpper(strings.TrimSuffix(l.EnvPrefix, "_")) + "_"
+
+	for _, e := range os.Environ() {
+		k, v, ok := strings.Cut(e, "=")
+		if !ok || !strings.HasPrefix(k, prefix) {
+			continue
+		}
+
+		name := strings.ToLower(strings.TrimPrefix(k, prefix))
+		key, found := findConfigKeyEnv(name)
+		if !found {
+			lerr.Unknown = append(lerr.Unknown, k)
+			continue
+		}
+
+		values[key] = v
+	}
+}
+
+func findConfigKey(name string) (configKey, bool) {
+	for _, k := range configKeys {
+		if k.name == name {
+			return k, true
+		}
+	}
+
+	return configKey{}, false
+}
+
+func findConfigKeyEnv(name string) (string, bool) {
+	for _, k := range configKeys {
+		if strings.ReplaceAll(k.name, ".", "_") == name {
+			return k.name, true
+		}
+	}
+
+	return "", false
+}
+
+func parseBool(v string, b *bool) error {
+	p, err := strconv.ParseBool(v)
+	if err != nil {
+		return err
+	}
+
+	*b = p
+
+	return nil
+}
+
+func parseMetaFields(v string) (MetaField, error) {
+	var m MetaField
+
+	for _, f := range strings.Split(v, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+
+		mf, ok := metaFieldNames[f]
+		if !ok {
+			return 0, fmt.Errorf("unknown meta field %s", f)
+		}
+
+		m |= mf
+	}
+
+	return m, nil
+}
@@ -0,0 +1,104 @@
+package rgroup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigLoader(t *testing.T) {
+	defer Config.Reset()
+
+	file := filepath.Join(t.TempDir(), "rgroup.json")
+	err := os.WriteFile(file, []byte(`{
+		"forward_error_log": true,
+		"envelope": {
+			"enabled": true,
+			"forward_http_status": true,
+			"meta": ["request_id", "duration"],
+			"api_version": "v1"
+		}
+	}`), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write config file: %s", err)
+	}
+
+	t.Setenv("RGROUP_ENVELOPE_API_VERSION", "v2")
+	t.Setenv("RGROUP_LOG_OPTIONS_REQUESTS", "false")
+
+	changes := 0
+	remove := Config.OnChange(func() { changes++ })
+	defer remove()
+
+	l := ConfigLoader{File: file, EnvPrefix: "RGROUP"}
+	if err := l.Load(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	c := loadConfig()
+	switch {
+	case !c.forwardErrorLog || c.logOptions:
+		t.Logf("unexpected config: %+v", c)
+		t.Fail()
+	case !c.Envelope.enabled || !c.Envelope.forwardHTTPStatus || c.Envelope.forwardLogMessage:
+		t.Logf("unexpected envelope config: %+v", c.Envelope)
+		t.Fail()
+	case c.Envelope.meta != MetaRequestID|MetaDuration || c.Envelope.apiVersion != "v2":
+		t.Logf("unexpected envelope meta config: %+v", c.Envelope)
+		t.Fail()
+	case changes != 1:
+		t.Logf("unexpected number of changes: %d", changes)
+		t.Fail()
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Setenv("RGROUP_ENVELOPE_ENABLED", "maybe")
+		t.Setenv("RGROUP_UNKNOWN", "1")
+
+		err := l.Load()
+
+		lerr := new(ConfigLoadError)
+		if !errors.As(err, &lerr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(lerr.Unknown) != 1 || lerr.Unknown[0] != "RGROUP_UNKNOWN" || len(lerr.Invalid) != 1 {
+			t.Logf("unexpected error: %s", lerr)
+			t.Fail()
+		}
+
+		if changes != 1 {
+			t.Logf("unexpected number of changes: %d", changes)
+			t.Fail()
+		}
+	})
+
+	t.Run("removed key", func(t *testing.T) {
+		if err := os.WriteFile(file, []byte(`{"envelope": {"enabled": true}}`), 0o600); err != nil {
+			t.Fatalf("failed to write config file: %s", err)
+		}
+
+		if err := l.Load(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		c := loadConfig()
+		switch {
+		case c.forwardErrorLog != defaultConfig.forwardErrorLog || c.Envelope.forwardHTTPStatus || c.Envelope.meta != 0:
+			t.Logf("removed keys not reset: %+v", c)
+			t.Fail()
+		case !c.Envelope.enabled || c.Envelope.apiVersion != "v2" || c.logOptions:
+			t.Logf("unexpected config: %+v", c)
+			t.Fail()
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		l := ConfigLoader{File: filepath.Join(t.TempDir(), "missing.json")}
+		if err := l.Load(); err == nil {
+			t.Log("expected error")
+			t.Fail()
+		}
+	})
+}
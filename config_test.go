@@ -202,4 +202,85 @@ func TestLockOnMake(t *testing.T) {
 		Config.lockOnMake = true
 	})
 
+	t.Run("mux options", func(t *testing.T) {
+		g := New()
+		g.Get(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return Response("test"), nil
+		})
+
+		mux := NewServeMux().SetOptions(NewOptions().LockOnMake(false).SetStrict(true))
+		mux.Handle("/", g)
+		h := mux.Make()
+
+		g.Post(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return Response("post"), nil
+		})
+
+		if err := g.Validate(); err != nil {
+			t.Logf("unexpected error: %s", err)
+			t.Fail()
+		}
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", nil))
+		if rr.Body.String() != "post" {
+			t.Logf("unexpected response: %s", rr.Body.String())
+			t.Fail()
+		}
+
+		defer func() {
+			if recover() == nil {
+				t.Log("expected panic for the strict mux")
+				t.Fail()
+			}
+		}()
+		g.Post(nil)
+	})
+}
+
+func TestConfigHotReload(t *testing.T) {
+	defer Config.Reset()
+
+	changes := 0
+	remove := Config.OnChange(func() { changes++ })
+
+	g := New().SetOptions(NewOptions().SetLogger(nil))
+	g.Get(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response("test").WithMessage("test message"), nil
+	})
+	h := g.Make()
+
+	Config.Envelope.Enable()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}
+	}()
+
+	for i := 0; i < 10; i++ {
+		Config.Envelope.SetForwardLogMessage(i%2 == 0)
+	}
+	<-done
+
+	rr := httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rr.Body.String() != "{\"data\":\"test\",\"status\":{\"http_status\":200}}" {
+		t.Logf("unexpected response: %s", rr.Body.String())
+		t.Fail()
+	}
+
+	if changes != 11 {
+		t.Logf("unexpected number of changes: %d", changes)
+		t.Fail()
+	}
+
+	remove()
+	Config.Envelope.Disable()
+	if changes != 11 {
+		t.Logf("unexpected number of changes after removing hook: %d", changes)
+		t.Fail()
+	}
 }
@@ -0,0 +1,216 @@
+package rgroup
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Allow routes to be added, replaced and removed after Make.
+// Every change rebuilds the route table of the mux, which is swapped atomically;
+// requests in flight complete with the table they started with.
+// A dynamic mux should only be added to a single enclosing mux.
+// Must be called before Make.
+func (m *HandlerMux) SetDynamic(b bool) *HandlerMux {
+	m.dynamic = b
+	return m
+}
+
+// Remove the route registered for path and return whether it existed.
+// Method-qualified patterns, e.g. DELETE /items/{id}, only remove the handler for the method.
+// Routes can only be removed from a dynamic mux after Make.
+func (m *HandlerMux) Remove(path string) bool {
+	var removed bool
+
+	m.update(func() {
+		if m.made() && !m.dynamic {
+			m.fail(fmt.Errorf("handler for %s removed after Make", path))
+			return
+		}
+
+		removed = m.remove(path)
+	})
+
+	return removed
+}
+
+// Replace the route registered for path with h, or add it if it does not exist.
+// The route keeps its name. If h or the pattern is invalid, the registered route is kept.
+// Routes can only be replaced on a dynamic mux after Make.
+func (m *HandlerMux) Replace(path string, h http.Handler) {
+	m.update(func() {
+		if m.made() && !m.dynamic {
+			m.fail(fmt.Errorf("handler for %s replaced after Make", path))
+			return
+		}
+
+		if err := validRoute(path, h); err != nil {
+			m.fail(err)
+			return
+		}
+
+		_, p := splitPattern(path)
+		names := make([]string, 0)
+		for n, np := range m.names {
+			if np == p {
+				names = append(names, n)
+			}
+		}
+
+		m.remove(path)
+		m.handle(path, h)
+
+		for _, n := range names {
+			m.names[n] = p
+		}
+	})
+}
+
+// validRoute returns the error handle would record for registering h at path, other than duplicates.
+func validRoute(path string, h http.Handler) error {
+	method, p := splitPattern(path)
+
+	switch h.(type) {
+	case nil:
+		return fmt.Errorf("nil handler for %s", path)
+	case *HandlerGroup, *HandlerMux:
+		if method != "" {
+			return fmt.Errorf("%s %s: method patterns can only be used with handlers", method, p)
+		}
+	}
+
+	if method != "" && !validMethod(method) {
+		return fmt.Errorf("invalid method %q", method)
+	}
+
+	if hasParams(p) {
+		if _, err := parsePattern(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *HandlerMux) remove(path string) bool {
+	method, p := splitPattern(path)
+
+	if method != "" {
+		g, ok := m.methods[p]
+		if !ok {
+			return false
+		}
+
+		if _, ok := g.handlers[method]; !ok {
+			return false
+		}
+
+		if len(g.handlers) > 1 {
+			g = cloneGroup(g)
+			delete(g.handlers, method)
+			m.methods[p] = g
+			m.h[p] = g
+
+			return true
+		}
+	}
+
+	if _, ok := m.h[p]; !ok {
+		return false
+	}
+
+	delete(m.h, p)
+	delete(m.methods, p)
+
+	for n, np := range m.names {
+		if np == p {
+			delete(m.names, n)
+		}
+	}
+
+	return true
+}
+
+// update runs f with the routes of a dynamic mux locked,
+// then rebuilds the route table if the mux has been built.
+func (m *HandlerMux) update(f func()) {
+	if !m.dynamic {
+		f()
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f()
+
+	if m.built {
+		m.table.Store(m.buildTable(m.parent))
+	}
+}
+
+// rlock locks the routes of a dynamic mux for reading and returns the unlock function.
+func (m *HandlerMux) rlock() func() {
+	if !m.dynamic {
+		return func() {}
+	}
+
+	m.mu.RLock()
+
+	return m.mu.RUnlock
+}
+
+// buildDynamic builds the route table of a dynamic mux and returns a handler serving the current table.
+func (m *HandlerMux) buildDynamic(parent buildContext) http.Handler {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.built = true
+	m.parent = parent
+	m.table.Store(m.buildTable(parent))
+
+	return dynamicTable{m}
+}
+
+type dynamicTable struct {
+	m *HandlerMux
+}
+
+func (d dynamicTable) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	d.m.table.Load().(*muxTable).ServeHTTP(w, req)
+}
+
+// cloneGroup returns an unbuilt copy of g with the same handlers, middleware, options and metadata.
+func cloneGroup(g *HandlerGroup) *HandlerGroup {
+	c := New()
+	for k, f := range g.handlers {
+		c.handlers[k] = f
+	}
+
+	for v, handlers := range g.versions {
+		for k, f := range handlers {
+			c.addHandler(v, k, f)
+		}
+	}
+
+	c.logger = g.logger
+	c.middleware = append([]Middleware(nil), g.middleware...)
+	c.tags = append([]string(nil), g.tags...)
+	c.pre = append([]Middleware(nil), g.pre...)
+	c.hooks = append([]hook(nil), g.hooks...)
+
+	// the maps are copied, as they are guarded by the lock of their group
+	for method, names := range g.skip {
+		for n := range names {
+			c.SkipFor(method, n)
+		}
+	}
+
+	for n := range g.disabled {
+		c.DisableHooks(n)
+	}
+
+	c.options = g.options
+	c.metadata = g.metadata
+
+	return c
+}
@@ -0,0 +1,132 @@
+package rgroup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestDynamicMux(t *testing.T) {
+	Config.Reset()
+	defer Config.Reset()
+	Config.SetGlobalLogger(nil)
+
+	handler := func(s string) Handler {
+		return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return Response(s), nil
+		}
+	}
+
+	get := func(h http.Handler, method string, path string) (int, string) {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(method, path, nil))
+		return res.Code, res.Body.String()
+	}
+
+	plugins := NewServeMux().SetPrefix("/plugins").SetDynamic(true)
+	plugins.HandleFunc("GET /a", handler("a"))
+
+	mux := NewServeMux()
+	mux.Handle("/plugins/", plugins)
+	h := mux.Make()
+
+	if code, body := get(h, http.MethodGet, "/plugins/a"); code != http.StatusOK || body != "a" {
+		t.Fatalf("unexpected response %d %s", code, body)
+	}
+
+	plugins.HandleFunc("/b/{id}", handler("b"))
+	plugins.HandleFunc("DELETE /a", handler("delete a"))
+
+	if code, body := get(h, http.MethodGet, "/plugins/b/1"); code != http.StatusOK || body != "b" {
+		t.Logf("added route: unexpected response %d %s", code, body)
+		t.Fail()
+	}
+
+	if code, body := get(h, http.MethodDelete, "/plugins/a"); code != http.StatusOK || body != "delete a" {
+		t.Logf("added method: unexpected response %d %s", code, body)
+		t.Fail()
+	}
+
+	plugins.Replace("/b/{id}", handler("b2"))
+	if _, body := get(h, http.MethodGet, "/plugins/b/1"); body != "b2" {
+		t.Logf("replaced route: unexpected response %s", body)
+		t.Fail()
+	}
+
+	// invalid replacements keep the registered route
+	plugins.Replace("/b/{id}", nil)
+	plugins.Replace("/b/{id", handler("invalid"))
+	if _, body := get(h, http.MethodGet, "/plugins/b/1"); body != "b2" {
+		t.Logf("invalid replacement: unexpected response %s", body)
+		t.Fail()
+	}
+
+	if err := plugins.Validate(); err == nil || len(err.(*ValidationError).Errors) != 2 {
+		t.Logf("expected 2 validation errors, got %v", err)
+		t.Fail()
+	}
+	plugins.errs = nil
+
+	if !plugins.Remove("DELETE /a") || plugins.Remove("DELETE /a") {
+		t.Logf("unexpected result removing DELETE /a")
+		t.Fail()
+	}
+
+	if code, _ := get(h, http.MethodDelete, "/plugins/a"); code != http.StatusMethodNotAllowed {
+		t.Logf("removed method: expected 405, got %d", code)
+		t.Fail()
+	}
+
+	if !plugins.Remove("/b/{id}") {
+		t.Logf("unexpected result removing /b/{id}")
+		t.Fail()
+	}
+
+	if code, _ := get(h, http.MethodGet, "/plugins/b/1"); code != http.StatusNotFound {
+		t.Logf("removed route: expected 404, got %d", code)
+		t.Fail()
+	}
+
+	// static muxes can not be changed after Make
+	mux.Remove("/plugins/")
+	if err := mux.Validate(); err == nil {
+		t.Logf("expected validation error")
+		t.Fail()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				get(h, http.MethodGet, "/plugins/a")
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				plugins.Replace("/c", handler("c"))
+				plugins.Routes()
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				plugins.Mount("/m", nil)
+				plugins.HandleFunc("/f", nil)
+				_ = plugins.Validate()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if code, body := get(h, http.MethodGet, "/plugins/c"); code != http.StatusOK || body != "c" {
+		t.Logf("unexpected response %d %s", code, body)
+		t.Fail()
+	}
+}
@@ -0,0 +1,169 @@
+package rgroup
+
+import (
+	"net/http"
+	"time"
+)
+
+// EnvelopeBuilder creates the payload sent to the client when envelope responses are enabled.
+// The returned value is written using the same rules as HandlerResponse.Data
+// (strings and byte slices are written as-is, anything else is JSON encoded).
+type EnvelopeBuilder interface {
+	// FromResponse builds the envelope for a successful response.
+	FromResponse(res *HandlerResponse, forwardLogMessage bool) any
+	// FromError builds the envelope for an error response.
+	FromError(err *HandlerError, forwardLogMessage bool) any
+}
+
+// EnvelopeContentTyper can be implemented by an EnvelopeBuilder to set the
+// Content-Type header of enveloped responses.
+type EnvelopeContentTyper interface {
+	ContentType() string
+}
+
+// DefaultEnvelopeBuilder returns the builder producing the default Envelope structure.
+func DefaultEnvelopeBuilder() EnvelopeBuilder {
+	return defaultEnvelopeBuilder{}
+}
+
+type defaultEnvelopeBuilder struct{}
+
+func (defaultEnvelopeBuilder) FromResponse(res *HandlerResponse, forwardLogMessage bool) any {
+	return newResponseEnvelope(res, forwardLogMessage)
+}
+
+func (defaultEnvelopeBuilder) FromError(err *HandlerError, forwardLogMessage bool) any {
+	return newErrorEnvelope(err, forwardLogMessage)
+}
+
+// writeEnvelope writes the envelope env created by builder b.
+func writeEnvelope(w http.ResponseWriter, b EnvelopeBuilder, env any, status int) int {
+	if ct, ok := b.(EnvelopeContentTyper); ok {
+		w.Header().Set("Content-Type", ct.ContentType())
+	}
+
+	if status != 0 {
+		w.WriteHeader(status)
+	}
+
+	return write(w, env)
+}
+
+func newResponseEnvelope(r *HandlerResponse, forwardLogMessage bool) *Envelope {
+	e := Envelope{
+		Data: r.Data,
+		Status: EnvelopeStatus{
+			HTTPStatus: r.HTTPStatus,
+			Message:    nil,
+			Error:      nil,
+		},
+		Meta: r.Meta,
+	}
+
+	if forwardLogMessage && r.LogMessage != "" {
+		e.Status.Message = toPtr(r.LogMessage)
+	}
+
+	return &e
+}
+
+func newErrorEnvelope(e *HandlerError, forwardLogMessage bool) *Envelope {
+	env := Envelope{
+		Data: nil,
+		Status: EnvelopeStatus{
+			HTTPStatus: e.HTTPStatus,
+			Message:    nil,
+			Error:      nil,
+		},
+		Meta: e.Meta,
+	}
+
+	if e.Response != "" {
+		env.Status.Error = toPtr(e.Response)
+	} else {
+		statusText := http.StatusText(e.HTTPStatus)
+		if statusText != "" {
+			env.Status.Error = &statusText
+		} else {
+			env.Status.Error = toPtr("unkown error")
+		}
+	}
+
+	if forwardLogMessage {
+		env.Status.Message = toPtr(e.Error())
+	}
+
+	return &env
+}
+
+// MetaField selects the meta entries populated by rgroup on enveloped responses.
+// Fields can be combined with a bitwise OR.
+type MetaField uint8
+
+const (
+	// Request ID read from the request header set with Config.Envelope.SetRequestIDHeader.
+	MetaRequestID MetaField = 1 << iota
+	// Server time (RFC3339) at which the response was written.
+	MetaServerTime
+	// Time taken to process the request in nanoseconds, up to writing the response.
+	MetaDuration
+	// API version set with Config.Envelope.SetAPIVersion.
+	MetaAPIVersion
+)
+
+// Meta keys used for the entries populated by rgroup.
+const (
+	MetaKeyRequestID  = "request_id"
+	MetaKeyServerTime = "server_time"
+	MetaKeyDuration   = "duration"
+	MetaKeyAPIVersion = "api_version"
+)
+
+// collectMeta returns the meta entries populated by rgroup for the request.
+func collectMeta(l *LoggerData, e envelopeOptions) map[string]any {
+	if e.meta == 0 {
+		return nil
+	}
+
+	meta := make(map[string]any)
+
+	if e.meta&MetaRequestID != 0 {
+		if id := l.Request.Header.Get(e.requestIDHeader); id != "" {
+			meta[MetaKeyRequestID] = id
+		}
+	}
+
+	if e.meta&MetaServerTime != 0 {
+		meta[MetaKeyServerTime] = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	if e.meta&MetaDuration != 0 {
+		// not cached, so the logged duration still includes writing the response
+		meta[MetaKeyDuration] = time.Now().UnixNano() - l.Timestamp
+	}
+
+	if e.meta&MetaAPIVersion != 0 && e.apiVersion != "" {
+		meta[MetaKeyAPIVersion] = e.apiVersion
+	}
+
+	return meta
+}
+
+// addMeta adds rgroup meta entries to m without overwriting entries set by handlers or middleware.
+func addMeta(m map[string]any, meta map[string]any) map[string]any {
+	if len(meta) == 0 {
+		return m
+	}
+
+	if m == nil {
+		m = make(map[string]any, len(meta))
+	}
+
+	for k, v := range meta {
+		if _, ok := m[k]; !ok {
+			m[k] = v
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,244 @@
+package rgroup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testEnvelopeError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type testEnvelope struct {
+	Ok     bool               `json:"ok"`
+	Result any                `json:"result,omitempty"`
+	Error  *testEnvelopeError `json:"error,omitempty"`
+}
+
+type testEnvelopeBuilder struct{}
+
+func (testEnvelopeBuilder) FromResponse(res *HandlerResponse, forwardLogMessage bool) any {
+	return testEnvelope{Ok: true, Result: res.Data}
+}
+
+func (testEnvelopeBuilder) FromError(err *HandlerError, forwardLogMessage bool) any {
+	e := testEnvelopeError{Code: err.HTTPStatus, Message: err.Response}
+	if forwardLogMessage {
+		e.Message = err.Error()
+	}
+
+	return testEnvelope{Ok: false, Error: &e}
+}
+
+func TestEnvelopeBuilder(t *testing.T) {
+	Config.Envelope.Enable()
+	Config.Envelope.SetBuilder(testEnvelopeBuilder{})
+	defer Config.Reset()
+
+	t.Run("response", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		writeRes(rr, Response("test data"), &Config)
+
+		if rr.Body.String() != "{\"ok\":true,\"result\":\"test data\"}" {
+			t.Logf("unexpected response: %s", rr.Body.String())
+			t.Fail()
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		writeErr(rr, Error(http.StatusNotFound).WithResponse("not found").WithMessage("test error"), &Config)
+
+		if rr.Body.String() != "{\"ok\":false,\"error\":{\"code\":404,\"message\":\"not found\"}}" {
+			t.Logf("unexpected response: %s", rr.Body.String())
+			t.Fail()
+		}
+	})
+
+	t.Run("forward log message", func(t *testing.T) {
+		Config.Envelope.SetForwardLogMessage(true)
+		rr := httptest.NewRecorder()
+		writeErr(rr, Error(http.StatusNotFound).WithResponse("not found").WithMessage("test error"), &Config)
+
+		if rr.Body.String() != "{\"ok\":false,\"error\":{\"code\":404,\"message\":\"test error\"}}" {
+			t.Logf("unexpected response: %s", rr.Body.String())
+			t.Fail()
+		}
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		Config.Envelope.SetBuilder(nil)
+		Config.Envelope.SetForwardLogMessage(false)
+		rr := httptest.NewRecorder()
+		writeRes(rr, Response("test data"), &Config)
+
+		if rr.Body.String() != "{\"data\":\"test data\",\"status\":{\"http_status\":200}}" {
+			t.Logf("unexpected response: %s", rr.Body.String())
+			t.Fail()
+		}
+	})
+}
+
+func TestEnvelopeMeta(t *testing.T) {
+	Config.Envelope.Enable()
+	Config.Envelope.SetMeta(MetaRequestID | MetaDuration | MetaAPIVersion | MetaServerTime)
+	Config.Envelope.SetAPIVersion("v1")
+	defer Config.Reset()
+
+	var meta map[string]any
+
+	g := New()
+	g.SetLogger(func(ld *LoggerData) {
+		if ld.Error != nil {
+			meta = ld.Error.Meta
+		} else {
+			meta = ld.Response.Meta
+		}
+	})
+	g.Get(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response("test").WithMeta("next", "abc"), nil
+	})
+	g.Post(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return nil, Error(http.StatusBadRequest).WithMeta(MetaKeyAPIVersion, "override")
+	})
+	h := g.Make()
+
+	t.Run("response", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Request-ID", "req-1")
+
+		h(rr, req)
+
+		switch {
+		case meta["next"] != "abc":
+			t.Logf("unexpected handler meta: %v", meta)
+			t.Fail()
+		case meta[MetaKeyRequestID] != "req-1":
+			t.Logf("unexpected request id: %v", meta[MetaKeyRequestID])
+			t.Fail()
+		case meta[MetaKeyAPIVersion] != "v1":
+			t.Logf("unexpected api version: %v", meta[MetaKeyAPIVersion])
+			t.Fail()
+		case meta[MetaKeyServerTime] == nil || meta[MetaKeyDuration] == nil:
+			t.Logf("missing meta: %v", meta)
+			t.Fail()
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+
+		h(rr, req)
+
+		if _, ok := meta[MetaKeyRequestID]; ok {
+			t.Logf("unexpected request id: %v", meta[MetaKeyRequestID])
+			t.Fail()
+		}
+		if meta[MetaKeyAPIVersion] != "override" {
+			t.Logf("unexpected api version: %v", meta[MetaKeyAPIVersion])
+			t.Fail()
+		}
+	})
+
+	t.Run("duration not cached", func(t *testing.T) {
+		l := fromRequest(*httptest.NewRequest(http.MethodGet, "/", nil))
+		collectMeta(l, Config.Envelope)
+
+		if l.time {
+			t.Log("meta duration cached the logged duration")
+			t.Fail()
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		Config.Envelope.SetMeta(0)
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		h(rr, req)
+
+		if rr.Body.String() != "{\"data\":\"test\",\"status\":{\"http_status\":200},\"meta\":{\"next\":\"abc\"}}" {
+			t.Logf("unexpected response: %s", rr.Body.String())
+			t.Fail()
+		}
+	})
+}
+
+func TestEnvelopeOverride(t *testing.T) {
+	Config.Envelope.Enable()
+	defer Config.Reset()
+
+	handler := func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response("test").WithHTTPStatus(http.StatusAccepted).WithMessage("test message"), nil
+	}
+
+	t.Run("group", func(t *testing.T) {
+		g := New().SetEnvelope(false)
+		g.Get(handler)
+
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if rr.Body.String() != "test" || rr.Code != http.StatusAccepted {
+			t.Logf("unexpected response: %d %s", rr.Code, rr.Body.String())
+			t.Fail()
+		}
+	})
+
+	t.Run("mux", func(t *testing.T) {
+		g1 := New()
+		g1.Get(handler)
+		g2 := New().SetForwardLogMessage(false)
+		g2.Get(handler)
+
+		mux := NewServeMux().SetForwardHTTPStatus(true).SetForwardLogMessage(true)
+		mux.Handle("/g1", g1)
+		mux.Handle("/g2", g2)
+
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/g1", nil))
+
+		if rr.Body.String() != "{\"data\":\"test\",\"status\":{\"http_status\":202,\"message\":\"test message\"}}" || rr.Code != http.StatusAccepted {
+			t.Logf("unexpected response: %d %s", rr.Code, rr.Body.String())
+			t.Fail()
+		}
+
+		rr = httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/g2", nil))
+
+		if rr.Body.String() != "{\"data\":\"test\",\"status\":{\"http_status\":202}}" || rr.Code != http.StatusAccepted {
+			t.Logf("unexpected response: %d %s", rr.Code, rr.Body.String())
+			t.Fail()
+		}
+	})
+
+	t.Run("raw", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		writeRes(rr, Response("test").Raw(), &Config)
+
+		if rr.Body.String() != "test" {
+			t.Logf("unexpected response: %s", rr.Body.String())
+			t.Fail()
+		}
+
+		rr = httptest.NewRecorder()
+		writeRes(rr, Response([]byte("test")), &Config)
+
+		if rr.Body.String() != "test" {
+			t.Logf("unexpected response: %s", rr.Body.String())
+			t.Fail()
+		}
+
+		rr = httptest.NewRecorder()
+		writeErr(rr, Error(http.StatusBadRequest).WithResponse("bad request").Raw(), &Config)
+
+		if rr.Body.String() != "bad request" || rr.Code != http.StatusBadRequest {
+			t.Logf("unexpected response: %d %s", rr.Code, rr.Body.String())
+			t.Fail()
+		}
+	})
+}
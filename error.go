@@ -2,7 +2,6 @@ package rgroup
 
 import (
 	"fmt"
-	"net/http"
 )
 
 // Error struct that can be used to return additional info on Handler error
@@ -11,6 +10,11 @@ type HandlerError struct {
 	LogMessage string
 	Response   string
 	HTTPStatus int
+	Meta       map[string]any
+	raw        bool
+	// error already written to the client by a standard middleware
+	written bool
+	size    int
 }
 
 // Create new HandlerError with the specified http status code.
@@ -20,6 +24,7 @@ func Error(code int) *HandlerError {
 		err:        nil,
 		LogMessage: "",
 		Response:   "",
+		Meta:       nil,
 	}
 
 	return &e
@@ -40,6 +45,24 @@ func (e *HandlerError) WithResponse(response string, args ...any) *HandlerError
 	return e
 }
 
+// Add a meta entry to be included in enveloped responses.
+func (e *HandlerError) WithMeta(key string, value any) *HandlerError {
+	if e.Meta == nil {
+		e.Meta = make(map[string]any)
+	}
+
+	e.Meta[key] = value
+
+	return e
+}
+
+// Send the error as-is, bypassing envelope responses.
+func (e *HandlerError) Raw() *HandlerError {
+	e.raw = true
+
+	return e
+}
+
 func (e *HandlerError) Error() string {
 	if e.err != nil {
 		if e.LogMessage != "" {
@@ -64,29 +87,5 @@ func (e *HandlerError) Unwrap() error {
 
 // Create Envelope from error.
 func (e *HandlerError) ToEnvelope() *Envelope {
-	env := Envelope{
-		Data: nil,
-		Status: EnvelopeStatus{
-			HTTPStatus: e.HTTPStatus,
-			Message:    nil,
-			Error:      nil,
-		},
-	}
-
-	if e.Response != "" {
-		env.Status.Error = &e.Response
-	} else {
-		statusText := http.StatusText(e.HTTPStatus)
-		if statusText != "" {
-			env.Status.Error = &statusText
-		} else {
-			env.Status.Error = toPtr("unkown error")
-		}
-	}
-
-	if Config.Envelope.forwardLogMessage {
-		env.Status.Message = toPtr(e.Error())
-	}
-
-	return &env
+	return newErrorEnvelope(e, loadConfig().Envelope.forwardLogMessage)
 }
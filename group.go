@@ -1,8 +1,11 @@
 package rgroup
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // Middleware function signature
@@ -18,17 +21,33 @@ type HandlerGroup struct {
 	handlers   HandlerMap
 	logger     func(*LoggerData)
 	middleware []Middleware
+	options    Options
+	errs       []error
+	built      bool
+	metadata   map[string]any
+	versions   map[string]HandlerMap
+	pre        []Middleware
+	// names of the middleware, empty for unnamed middleware
+	tags []string
+	// names of the middleware skipped per method, "" for all methods
+	skip map[string]map[string]bool
+	// lifecycle hooks and the names of the hooks disabled for the group
+	hooks    []hook
+	disabled map[string]bool
+	// options inherited from the mux the group was last built in, used by locked and fail
+	parent Options
+	// incremented on every change, to recompile the middleware chains when not locked on Make
+	gen uint64
+	// guards the handlers, middleware and hooks read when recompiling the chains
+	mu sync.RWMutex
 }
 
-// MethodsAllowed returns a string slice with all http verbs handled by the group
+// MethodsAllowed returns a string slice with all http verbs handled by the group,
+// including the verbs of versioned handlers.
 func (h *HandlerGroup) MethodsAllowed() []string {
-	opts := make([]string, len(h.handlers)+1)
-	opts[0] = http.MethodOptions
-
-	i := 1
-	for k := range h.handlers {
-		opts[i] = k
-		i++
+	opts := []string{http.MethodOptions}
+	for _, k := range h.methods() {
+		opts = append(opts, k)
 	}
 
 	return opts
@@ -60,19 +79,116 @@ func (h *HandlerGroup) SetLogger(p func(*LoggerData)) {
 	h.logger = p
 }
 
+// Set the Options of the HandlerGroup.
+// Options are copied; unset values are inherited from the HandlerMux the group
+// is added to and finally from the global Config.
+func (h *HandlerGroup) SetOptions(o *Options) *HandlerGroup {
+	if o == nil {
+		o = NewOptions()
+	}
+
+	h.options = *o
+
+	return h
+}
+
+// locked reports whether the HandlerGroup can no longer be modified.
+func (h *HandlerGroup) locked() bool {
+	return (h.h != nil || h.built) && h.options.inherit(h.parent).apply(lockedConfig()).lockOnMake
+}
+
+// fail records a registration error, or panics in strict mode.
+func (h *HandlerGroup) fail(err error) {
+	if h.options.inherit(h.parent).apply(lockedConfig()).strict {
+		panic(fmt.Errorf("[rgroup] %w", err))
+	}
+
+	h.errs = append(h.errs, err)
+}
+
+// Enable or disable envelope responses for the HandlerGroup.
+// This overrides Config.Envelope and any HandlerMux the group is added to.
+func (h *HandlerGroup) SetEnvelope(enabled bool) *HandlerGroup {
+	h.options.SetEnvelope(enabled)
+
+	return h
+}
+
+// Forward the http status code to the client for the HandlerGroup.
+// This overrides Config.Envelope and any HandlerMux the group is added to.
+func (h *HandlerGroup) SetForwardHTTPStatus(b bool) *HandlerGroup {
+	h.options.SetForwardHTTPStatus(b)
+
+	return h
+}
+
+// Forward the log message to the client for the HandlerGroup.
+// This overrides Config.Envelope and any HandlerMux the group is added to.
+func (h *HandlerGroup) SetForwardLogMessage(b bool) *HandlerGroup {
+	h.options.SetForwardLogMessage(b)
+
+	return h
+}
+
+// Set the EnvelopeBuilder used for the HandlerGroup.
+// This overrides Config.Envelope and any HandlerMux the group is added to.
+func (h *HandlerGroup) SetEnvelopeBuilder(b EnvelopeBuilder) *HandlerGroup {
+	h.options.SetEnvelopeBuilder(b)
+
+	return h
+}
+
 // Adds a new Handler to the HandlerGroup.
 func (h *HandlerGroup) AddHandler(method string, handler Handler) {
-	if Config.lockOnMake && h.h != nil {
+	h.addHandler("", method, handler)
+}
+
+func (h *HandlerGroup) addHandler(version string, method string, handler Handler) {
+	m := strings.ToUpper(method)
+	if version != "" {
+		m = fmt.Sprintf("%s %s", version, m)
+	}
+
+	if h.locked() {
+		h.fail(fmt.Errorf("%s handler added after Make", m))
 		return
 	}
 
-	if h.handlers == nil {
+	switch {
+	case !validMethod(strings.ToUpper(method)):
+		h.fail(fmt.Errorf("invalid method %q", method))
+		return
+	case handler == nil:
+		h.fail(fmt.Errorf("nil %s handler", m))
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	handlers := h.handlers
+	if version != "" {
+		if h.versions == nil {
+			h.versions = make(map[string]HandlerMap)
+		}
+
+		if h.versions[version] == nil {
+			h.versions[version] = make(HandlerMap)
+		}
+
+		handlers = h.versions[version]
+	} else if handlers == nil {
 		h.handlers = make(HandlerMap)
+		handlers = h.handlers
 	}
 
-	m := strings.ToUpper(method)
+	method = strings.ToUpper(method)
+	if _, ok := handlers[method]; ok {
+		h.fail(fmt.Errorf("duplicate %s handler", m))
+	}
 
-	h.handlers[m] = handler
+	handlers[method] = handler
+	h.changed()
 }
 
 // Utility function to add POST Handler to HandlerGroup
@@ -102,49 +218,97 @@ func (h *HandlerGroup) Get(handler Handler) {
 
 // AddMiddleware appends the given Middleware to the HandlerGroup
 func (h *HandlerGroup) AddMiddleware(m ...Middleware) *HandlerGroup {
-	if Config.lockOnMake && h.h != nil {
+	if h.locked() {
+		h.fail(fmt.Errorf("middleware added after Make"))
 		return h
 	}
 
+	for _, mid := range m {
+		if mid == nil {
+			h.fail(fmt.Errorf("nil middleware"))
+			return h
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	if h.middleware == nil {
 		h.middleware = make([]Middleware, 0)
 	}
 
 	h.middleware = append(h.middleware, m...)
+	h.tags = append(h.tags, make([]string, len(m))...)
+	h.changed()
+
+	return h
+}
+
+// AddPreMiddleware appends Middleware wrapping the whole dispatch of the HandlerGroup,
+// including the built-in OPTIONS and 405 responses, method override and version selection.
+// Pre-middleware runs before the middleware added with AddMiddleware.
+func (h *HandlerGroup) AddPreMiddleware(m ...Middleware) *HandlerGroup {
+	if h.locked() {
+		h.fail(fmt.Errorf("pre-middleware added after Make"))
+		return h
+	}
+
+	for _, mid := range m {
+		if mid == nil {
+			h.fail(fmt.Errorf("nil pre-middleware"))
+			return h
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.pre = append(h.pre, m...)
+	h.changed()
 
 	return h
 }
 
 // Generates an http.HandlerFunc from the HandlerGroup.
 func (h *HandlerGroup) Make() http.HandlerFunc {
-	if Config.lockOnMake && h.h != nil {
+	if h.h != nil && h.locked() {
 		return h.h
 	}
 
-	// set handler request logger
-	if h.logger == nil {
-		h.logger = Config.logger
-	}
+	h.h = h.build(buildContext{})
 
+	return h.h
+}
+
+// build generates an http.HandlerFunc from the HandlerGroup with the middleware and options
+// inherited from the enclosing muxes. The HandlerGroup itself is not modified.
+func (h *HandlerGroup) build(ctx buildContext) http.HandlerFunc {
+	// set handler request logger
+	// the global logger is resolved on every request when not set
 	logger := h.logger
+	options := h.options.inherit(ctx.options)
+
+	// the chains are compiled once; without lockOnMake, changes after Make recompile them
+	var compiled atomic.Value
+	compiled.Store(h.compile(ctx, options, logger))
+	locked := options.apply(lockedConfig()).lockOnMake
 
-	h.h = func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
 		l := fromRequest(*req)
 
-		f, ok := h.handlers[req.Method]
-		switch {
-		case ok:
-			l.Response, l.err = f.applyMiddleware(h.middleware)(w, req)
-		case !ok && req.Method == http.MethodOptions:
-			l.Response = Response(nil).WithHeader("Allow", strings.Join(h.MethodsAllowed(), ","))
-		default:
-			l.err = Error(http.StatusMethodNotAllowed)
+		c := compiled.Load().(*chains)
+		if !locked && c.gen != atomic.LoadUint64(&h.gen) {
+			c = h.compile(ctx, options, logger)
+			compiled.Store(c)
 		}
 
-		logAndWrite(w, l, logger)
-	}
+		cfg := options.apply(*loadConfig())
+		req = withGroup(req, l, cfg)
 
-	return h.h
+		l.Response, l.err = c.serve(w, req)
+
+		logAndWrite(w, l, c.logger, cfg)
+	}
 }
 
 func (h *HandlerGroup) ServeHTTP(w http.ResponseWriter, req *http.Request) {
@@ -328,6 +328,8 @@ func TestNetHttpHandler(t *testing.T) {
 }
 
 func TestGroupPrewriter(t *testing.T) {
+	defer Config.Reset()
+
 	Config.SetGlobalLogger(func(ld *LoggerData) { fmt.Println(ld.Message()) })
 	Config.SetPrewriter(func(r *http.Request, hr *HandlerResponse) *HandlerResponse {
 		return Response(hr.Data).WithMessage("test prewriter")
@@ -350,3 +352,148 @@ func TestGroupPrewriter(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestCompiledChains(t *testing.T) {
+	unlocked := NewOptions().LockOnMake(false)
+
+	var wrapped int
+	tag := func(s string) Middleware {
+		return func(next Handler) Handler {
+			wrapped++
+			return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+				res, err := next(w, req)
+				res.Data = fmt.Sprintf("%v %s", res.Data, s)
+				return res, err
+			}
+		}
+	}
+
+	g := New().SetOptions(unlocked).AddMiddleware(tag("g1")).AddPreMiddleware(tag("pre"))
+	g.Get(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) { return Response("test"), nil })
+
+	h := g.Make()
+	serve := func() string {
+		rr := httptest.NewRecorder()
+		h(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rr.Body.String()
+	}
+
+	for i := 0; i < 3; i++ {
+		if res := serve(); res != "test g1 pre" {
+			t.Logf("unexpected response: %s", res)
+			t.Fail()
+		}
+	}
+
+	if wrapped != 2 {
+		t.Logf("middleware applied %d times", wrapped)
+		t.Fail()
+	}
+
+	g.AddMiddleware(tag("g2"))
+
+	if res := serve(); res != "test g1 g2 pre" {
+		t.Logf("unexpected response after AddMiddleware: %s", res)
+		t.Fail()
+	}
+
+	t.Run("concurrent registration", func(t *testing.T) {
+		g := New().SetOptions(unlocked)
+		g.Get(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) { return Response("test"), nil })
+		h := g.Make()
+
+		started, stop, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(done)
+			h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			close(started)
+
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+				}
+			}
+		}()
+		<-started
+
+		f := func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) { return nil, nil }
+		for i := 0; i < 100; i++ {
+			g.AddMiddleware(func(next Handler) Handler { return next })
+			g.AddHandler(fmt.Sprintf("M%d", i), f)
+			g.SkipFor(http.MethodGet, "none")
+			g.OnRequest(fmt.Sprintf("r%d", i), func(*http.Request) error { return nil })
+		}
+		close(stop)
+		<-done
+
+		rr := httptest.NewRecorder()
+		h(rr, httptest.NewRequest("M99", "/", nil))
+		if rr.Code != http.StatusOK {
+			t.Logf("unexpected status %d", rr.Code)
+			t.Fail()
+		}
+	})
+}
+
+func BenchmarkMake(b *testing.B) {
+	mid := func(next Handler) Handler {
+		return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return next(w, req)
+		}
+	}
+
+	f := func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response(nil).WithHTTPStatus(http.StatusNoContent), nil
+	}
+
+	group := func() *HandlerGroup {
+		g := New().AddMiddleware(mid, mid, mid, mid, mid).AddPreMiddleware(mid, mid)
+		g.OnRequest("request", func(*http.Request) error { return nil }).
+			OnResponse("response", func(*http.Request, *HandlerResponse) {}).
+			OnLog("log", func(*LoggerData) {})
+		g.Get(f)
+
+		return g
+	}
+
+	run := func(b *testing.B, h http.Handler) {
+		Config.SetGlobalLogger(func(*LoggerData) {})
+		defer Config.Reset()
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			h.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}
+
+	b.Run("group", func(b *testing.B) {
+		run(b, group().Make())
+	})
+
+	b.Run("per-request", func(b *testing.B) {
+		// baseline: the group above, composing the middleware, pre-middleware and hooks on every request
+		hooks := group().hooks
+		g := New().OnLog("log", func(*LoggerData) {})
+		g.Get(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			chain := Handler(f).applyMiddleware([]Middleware{mid, mid, mid, mid, mid}).applyMiddleware([]Middleware{mid, mid})
+			return wrapHooks(hooks, chain)(w, req)
+		})
+
+		run(b, g.Make())
+	})
+
+	b.Run("mux", func(b *testing.B) {
+		m := NewServeMux().AddMiddleware(mid, mid).AddPreMiddleware(mid, mid)
+		m.OnRequest("mux", func(*http.Request) error { return nil })
+		m.Handle("/", group())
+
+		run(b, m.Make())
+	})
+}
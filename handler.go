@@ -17,14 +17,21 @@ func (h Handler) applyMiddleware(middleware []Middleware) Handler {
 }
 
 func (h Handler) ToHandlerFunc() http.HandlerFunc {
+	return h.toHandlerFunc(Options{})
+}
 
-	logger := Config.logger
-
+func (h Handler) toHandlerFunc(o Options) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		l := fromRequest(*req)
+		c := o.apply(*loadConfig())
+		req = withGroup(req, l, c)
 
 		l.Response, l.err = h(w, req)
 
-		logAndWrite(w, l, logger)
+		logAndWrite(w, l, nil, c)
 	}
 }
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.ToHandlerFunc()(w, req)
+}
@@ -0,0 +1,240 @@
+package rgroup
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// RequestHook is called before a HandlerGroup dispatches a request.
+// A non-nil error aborts the request and is returned to the client.
+type RequestHook func(req *http.Request) error
+
+// ResponseHook is called after a request was handled without error.
+// The HandlerResponse may be nil if the handler wrote the response itself.
+type ResponseHook func(req *http.Request, res *HandlerResponse)
+
+// ErrorHook is called after a request failed with an error.
+type ErrorHook func(req *http.Request, err error)
+
+// LogHook is called with the LoggerData of a request before it is passed to the logger.
+// It is not called for requests that are not logged, e.g. OPTIONS requests when disabled.
+type LogHook func(l *LoggerData)
+
+// hook is a named lifecycle hook; exactly one of the functions is set.
+type hook struct {
+	name     string
+	request  RequestHook
+	response ResponseHook
+	err      ErrorHook
+	log      LogHook
+}
+
+// event returns the lifecycle event the hook runs on.
+func (k hook) event() string {
+	switch {
+	case k.request != nil:
+		return "request"
+	case k.response != nil:
+		return "response"
+	case k.err != nil:
+		return "error"
+	default:
+		return "log"
+	}
+}
+
+// newHook validates a hook of the given event.
+func newHook(event string, k hook, isNil bool) (hook, error) {
+	switch {
+	case k.name == "":
+		return k, fmt.Errorf("empty %s hook name", event)
+	case isNil:
+		return k, fmt.Errorf("nil %s hook %s", event, k.name)
+	}
+
+	return k, nil
+}
+
+// OnRequest adds a named RequestHook to the HandlerGroup.
+func (h *HandlerGroup) OnRequest(name string, f RequestHook) *HandlerGroup {
+	return h.addHook(newHook("request", hook{name: name, request: f}, f == nil))
+}
+
+// OnResponse adds a named ResponseHook to the HandlerGroup.
+func (h *HandlerGroup) OnResponse(name string, f ResponseHook) *HandlerGroup {
+	return h.addHook(newHook("response", hook{name: name, response: f}, f == nil))
+}
+
+// OnError adds a named ErrorHook to the HandlerGroup.
+func (h *HandlerGroup) OnError(name string, f ErrorHook) *HandlerGroup {
+	return h.addHook(newHook("error", hook{name: name, err: f}, f == nil))
+}
+
+// OnLog adds a named LogHook to the HandlerGroup.
+func (h *HandlerGroup) OnLog(name string, f LogHook) *HandlerGroup {
+	return h.addHook(newHook("log", hook{name: name, log: f}, f == nil))
+}
+
+// DisableHooks disables the named hooks of the HandlerGroup and the enclosing muxes for the group.
+func (h *HandlerGroup) DisableHooks(names ...string) *HandlerGroup {
+	if h.locked() {
+		h.fail(fmt.Errorf("hooks disabled after Make"))
+		return h
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.disabled == nil {
+		h.disabled = make(map[string]bool)
+	}
+
+	for _, n := range names {
+		h.disabled[n] = true
+	}
+	h.changed()
+
+	return h
+}
+
+func (h *HandlerGroup) addHook(k hook, err error) *HandlerGroup {
+	switch {
+	case err != nil:
+		h.fail(err)
+	case h.locked():
+		h.fail(fmt.Errorf("%s hook %s added after Make", k.event(), k.name))
+	default:
+		h.mu.Lock()
+		h.hooks = append(h.hooks, k)
+		h.changed()
+		h.mu.Unlock()
+	}
+
+	return h
+}
+
+// OnRequest adds a named RequestHook to all handler groups of the mux and nested muxes.
+func (m *HandlerMux) OnRequest(name string, f RequestHook) *HandlerMux {
+	return m.addHook(newHook("request", hook{name: name, request: f}, f == nil))
+}
+
+// OnResponse adds a named ResponseHook to all handler groups of the mux and nested muxes.
+func (m *HandlerMux) OnResponse(name string, f ResponseHook) *HandlerMux {
+	return m.addHook(newHook("response", hook{name: name, response: f}, f == nil))
+}
+
+// OnError adds a named ErrorHook to all handler groups of the mux and nested muxes.
+func (m *HandlerMux) OnError(name string, f ErrorHook) *HandlerMux {
+	return m.addHook(newHook("error", hook{name: name, err: f}, f == nil))
+}
+
+// OnLog adds a named LogHook to all handler groups of the mux and nested muxes.
+func (m *HandlerMux) OnLog(name string, f LogHook) *HandlerMux {
+	return m.addHook(newHook("log", hook{name: name, log: f}, f == nil))
+}
+
+func (m *HandlerMux) addHook(k hook, err error) *HandlerMux {
+	switch {
+	case err != nil:
+		m.fail(err)
+	case m.made():
+		m.fail(fmt.Errorf("%s hook %s added after Make", k.event(), k.name))
+	default:
+		m.hooks = append(m.hooks, k)
+	}
+
+	return m
+}
+
+// enabledHooks returns the hooks of the enclosing muxes followed by the hooks of the group,
+// without the hooks disabled by the group.
+func (h *HandlerGroup) enabledHooks(ctx buildContext) []hook {
+	hooks := make([]hook, 0, len(ctx.hooks)+len(h.hooks))
+	for _, k := range ctx.hooks {
+		if !h.disabled[k.name] {
+			hooks = append(hooks, k)
+		}
+	}
+
+	for _, k := range h.hooks {
+		if !h.disabled[k.name] {
+			hooks = append(hooks, k)
+		}
+	}
+
+	return hooks
+}
+
+// wrapHooks returns next wrapped with the request, response and error hooks.
+func wrapHooks(hooks []hook, next Handler) Handler {
+	var active bool
+	for _, k := range hooks {
+		active = active || k.log == nil
+	}
+
+	if !active {
+		return next
+	}
+
+	return func(w http.ResponseWriter, req *http.Request) (res *HandlerResponse, err error) {
+		for _, k := range hooks {
+			if k.request != nil {
+				if err = k.request(req); err != nil {
+					break
+				}
+			}
+		}
+
+		if err == nil {
+			res, err = next(w, req)
+		}
+
+		for _, k := range hooks {
+			switch {
+			case err != nil && k.err != nil:
+				k.err(req, err)
+			case err == nil && k.response != nil:
+				k.response(req, res)
+			}
+		}
+
+		return res, err
+	}
+}
+
+// logHooks returns a logger running the log hooks before logger, or the global logger if nil.
+func logHooks(hooks []hook, logger func(*LoggerData)) func(*LoggerData) {
+	var active bool
+	for _, k := range hooks {
+		active = active || k.log != nil
+	}
+
+	if !active {
+		return logger
+	}
+
+	return func(l *LoggerData) {
+		for _, k := range hooks {
+			if k.log != nil {
+				k.log(l)
+			}
+		}
+
+		if logger != nil {
+			logger(l)
+			return
+		}
+
+		loadConfig().logger(l)
+	}
+}
+
+// hookNames returns the hooks in the order they run as "event:name".
+func hookNames(hooks []hook) []string {
+	names := make([]string, 0, len(hooks))
+	for _, k := range hooks {
+		names = append(names, k.event()+":"+k.name)
+	}
+
+	return names
+}
@@ -0,0 +1,123 @@
+package rgroup
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	Config.Reset()
+	defer Config.Reset()
+
+	var events []string
+	Config.SetGlobalLogger(func(l *LoggerData) { events = append(events, "logger") })
+
+	ok := func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		events = append(events, "handler")
+		return Response("ok"), nil
+	}
+
+	errForbidden := Error(http.StatusForbidden)
+
+	g := NewWithHandlers(HandlerMap{
+		http.MethodGet: ok,
+		http.MethodPost: func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return nil, errors.New("failed")
+		},
+	}).
+		OnRequest("group", func(req *http.Request) error {
+			events = append(events, "request:group")
+			return nil
+		}).
+		OnResponse("metrics", func(req *http.Request, res *HandlerResponse) {
+			events = append(events, "response:metrics")
+		}).
+		OnError("report", func(req *http.Request, err error) {
+			events = append(events, "error:"+err.Error())
+		}).
+		OnLog("audit", func(l *LoggerData) {
+			events = append(events, "log:audit")
+		})
+
+	quiet := NewWithHandlers(HandlerMap{http.MethodGet: ok}).DisableHooks("auth", "audit")
+
+	mux := NewServeMux().
+		OnRequest("auth", func(req *http.Request) error {
+			events = append(events, "request:auth")
+			if req.Header.Get("Authorization") == "" {
+				return errForbidden
+			}
+			return nil
+		}).
+		OnLog("audit", func(l *LoggerData) {
+			events = append(events, "log:mux")
+		})
+	mux.Handle("/g", g)
+	mux.Handle("/quiet", quiet)
+
+	tests := []struct {
+		method string
+		path   string
+		auth   bool
+		status int
+		events string
+	}{
+		{
+			method: http.MethodGet, path: "/g", auth: true, status: http.StatusOK,
+			events: "request:auth,request:group,handler,response:metrics,log:mux,log:audit,logger",
+		},
+		{
+			method: http.MethodPost, path: "/g", auth: true, status: http.StatusInternalServerError,
+			events: "request:auth,request:group,error:failed,log:mux,log:audit,logger",
+		},
+		{
+			method: http.MethodGet, path: "/g", status: http.StatusForbidden,
+			events: "request:auth,error:" + errForbidden.Error() + ",log:mux,log:audit,logger",
+		},
+		{method: http.MethodGet, path: "/quiet", status: http.StatusOK, events: "handler,logger"},
+	}
+
+	for _, tt := range tests {
+		events = nil
+
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.auth {
+			req.Header.Set("Authorization", "token")
+		}
+
+		res := httptest.NewRecorder()
+		mux.ServeHTTP(res, req)
+
+		if res.Code != tt.status || strings.Join(events, ",") != tt.events {
+			t.Logf("%s %s: unexpected response %d, events %v", tt.method, tt.path, res.Code, events)
+			t.Fail()
+		}
+	}
+
+	for _, r := range mux.Routes() {
+		hooks := strings.Join(r.Hooks, ",")
+		switch {
+		case r.Path == "/g" && hooks != "request:auth,log:audit,request:group,response:metrics,error:report,log:audit":
+			t.Logf("unexpected hooks %v", r.Hooks)
+			t.Fail()
+		case r.Path == "/quiet" && hooks != "":
+			t.Logf("unexpected hooks %v", r.Hooks)
+			t.Fail()
+		}
+	}
+
+	if err := New().OnRequest("", func(req *http.Request) error { return nil }).OnError("nil", nil).Validate(); err == nil {
+		t.Log("expected errors for invalid hooks")
+		t.Fail()
+	}
+
+	late := NewServeMux()
+	late.Make()
+	if err := late.OnLog("late", func(l *LoggerData) {}).Validate(); err == nil {
+		t.Log("expected error for hook added after Make")
+		t.Fail()
+	}
+}
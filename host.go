@@ -0,0 +1,197 @@
+package rgroup
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// hostPattern is a HandlerMux host pattern, e.g. api.example.com or {tenant}.example.com
+type hostPattern struct {
+	raw    string
+	labels []patternSegment
+}
+
+type hostRoute struct {
+	pattern *hostPattern
+	h       http.Handler
+}
+
+func parseHostPattern(p string) (*hostPattern, error) {
+	if p == "" {
+		return nil, fmt.Errorf("empty host pattern")
+	}
+
+	hp := &hostPattern{raw: strings.ToLower(p)}
+	names := make(map[string]bool)
+
+	for _, label := range strings.Split(hp.raw, ".") {
+		if !strings.ContainsAny(label, "{}") {
+			if label == "" {
+				return nil, fmt.Errorf("host pattern %s: empty label", p)
+			}
+			hp.labels = append(hp.labels, patternSegment{literal: label})
+			continue
+		}
+
+		if !strings.HasPrefix(label, "{") || !strings.HasSuffix(label, "}") {
+			return nil, fmt.Errorf("host pattern %s: parameter must be a full label", p)
+		}
+
+		name := label[1 : len(label)-1]
+		if !validParamName(name) {
+			return nil, fmt.Errorf("host pattern %s: invalid parameter name %q", p, name)
+		}
+
+		if names[name] {
+			return nil, fmt.Errorf("host pattern %s: duplicate parameter %s", p, name)
+		}
+		names[name] = true
+
+		hp.labels = append(hp.labels, patternSegment{param: name})
+	}
+
+	return hp, nil
+}
+
+// match matches host against the pattern and returns the host parameters.
+func (p *hostPattern) match(host string) (map[string]string, bool) {
+	labels := strings.Split(host, ".")
+	if len(labels) != len(p.labels) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, l := range p.labels {
+		switch {
+		case l.param != "":
+			if labels[i] == "" {
+				return nil, false
+			}
+			params[l.param] = labels[i]
+		case l.literal != labels[i]:
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// moreSpecific reports whether p should be matched before o.
+// Labels are compared from the top level domain down, literals before parameters.
+func (p *hostPattern) moreSpecific(o *hostPattern) bool {
+	for i, j := len(p.labels)-1, len(o.labels)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if r1, r2 := p.labels[i].rank(), o.labels[j].rank(); r1 != r2 {
+			return r1 < r2
+		}
+	}
+
+	return p.raw < o.raw
+}
+
+// Add a handler for requests to host.
+// The host pattern can contain parameters matching a full label, e.g. {tenant}.example.com,
+// read with HostParam. Ports are ignored and hosts are matched case-insensitively.
+//
+// Host routes are matched before the paths of the mux. Requests not matching any host
+// are rejected with SetUnmatchedHostStatus if set, and otherwise routed by path.
+func (m *HandlerMux) HandleHost(host string, h http.Handler) {
+	m.update(func() {
+		m.handleHost(host, h)
+	})
+}
+
+func (m *HandlerMux) handleHost(host string, h http.Handler) {
+	switch {
+	case m.made() && !m.dynamic:
+		m.fail(fmt.Errorf("handler for host %s added after Make", host))
+		return
+	case h == nil:
+		m.fail(fmt.Errorf("nil handler for host %s", host))
+		return
+	}
+
+	hp, err := parseHostPattern(host)
+	if err != nil {
+		m.fail(err)
+		return
+	}
+
+	for _, r := range m.hosts {
+		if r.pattern.raw == hp.raw {
+			m.fail(fmt.Errorf("duplicate handler for host %s", host))
+			return
+		}
+	}
+
+	m.hosts = append(m.hosts, hostRoute{pattern: hp, h: h})
+}
+
+// Set the status of the error returned for requests not matching any host, e.g. http.StatusMisdirectedRequest.
+// If not set, the requests are routed by path, or served by the NotFound handler of the mux
+// if no paths are registered.
+func (m *HandlerMux) SetUnmatchedHostStatus(status int) *HandlerMux {
+	m.hostStatus = status
+	return m
+}
+
+// buildHosts adds the host routes of m to t.
+func (m *HandlerMux) buildHosts(ctx buildContext, t *muxTable) {
+	if len(m.hosts) == 0 {
+		return
+	}
+
+	for _, r := range m.hosts {
+		t.hosts = append(t.hosts, hostRoute{pattern: r.pattern, h: buildRoute(ctx, r.h)})
+	}
+
+	sort.SliceStable(t.hosts, func(i, j int) bool {
+		return t.hosts[i].pattern.moreSpecific(t.hosts[j].pattern)
+	})
+
+	if m.hostStatus == 0 {
+		if len(m.h) == 0 {
+			t.unmatchedHost = t.notFound
+		}
+		return
+	}
+
+	status := m.hostStatus
+	t.unmatchedHost = buildRoute(ctx, Handler(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return nil, Error(status).WithMessage("no handler for host %s", requestHost(req))
+	}))
+}
+
+// requestHost returns the host of the request without the port, in lower case.
+func requestHost(req *http.Request) string {
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// withHost returns req with the host parameters added to the routing context.
+func withHost(req *http.Request, params map[string]string) *http.Request {
+	rc := &routeContext{host: params}
+
+	if parent := routeFrom(req.Context()); parent != nil {
+		*rc = *parent
+		rc.host = mergeParams(parent.host, params)
+	}
+
+	return req.WithContext(context.WithValue(req.Context(), routeContextKey{}, rc))
+}
+
+// HostParam returns the value of the named host parameter, or an empty string if it does not exist.
+func HostParam(req *http.Request, name string) string {
+	if rc := routeFrom(req.Context()); rc != nil {
+		return rc.host[name]
+	}
+
+	return ""
+}
@@ -0,0 +1,102 @@
+package rgroup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHostRouting(t *testing.T) {
+	Config.Reset()
+	defer Config.Reset()
+	Config.SetGlobalLogger(nil)
+
+	hostHandler := func(name string) Handler {
+		return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return Response(name + ":" + HostParam(req, "tenant") + ":" + PathParam(req, "id")), nil
+		}
+	}
+
+	admin := NewServeMux()
+	admin.HandleFunc("/users/{id}", hostHandler("admin"))
+
+	mux := NewServeMux()
+	mux.HandleHost("admin.example.com", admin)
+	mux.HandleHost("{tenant}.example.com", hostHandler("tenant"))
+	mux.HandleHost("{tenant}.example.com", hostHandler("dup"))
+	mux.HandleHost("x{y}.example.com", hostHandler("bad"))
+
+	if err := mux.Validate(); err == nil || len(err.(*ValidationError).Errors) != 2 {
+		t.Logf("expected 2 validation errors, got %v", err)
+		t.Fail()
+	}
+
+	tests := []struct {
+		host   string
+		path   string
+		status int
+		body   string
+	}{
+		{host: "admin.example.com:8080", path: "/users/7", status: http.StatusOK, body: "admin::7"},
+		{host: "ACME.example.com", path: "/anything", status: http.StatusOK, body: "tenant:acme:"},
+		{host: "example.com", path: "/users/7", status: http.StatusNotFound},
+	}
+
+	h := mux.Make()
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Host = tt.host
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		if res.Code != tt.status || !strings.Contains(res.Body.String(), tt.body) {
+			t.Logf("%s%s: unexpected response %d %s", tt.host, tt.path, res.Code, res.Body.String())
+			t.Fail()
+		}
+	}
+
+	// paths serve unmatched hosts
+	fallback := NewServeMux()
+	fallback.HandleHost("admin.example.com", hostHandler("admin"))
+	fallback.HandleFunc("/", hostHandler("default"))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "other.com"
+	res := httptest.NewRecorder()
+	fallback.ServeHTTP(res, req)
+
+	if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), "default") {
+		t.Logf("unexpected response %d %s", res.Code, res.Body.String())
+		t.Fail()
+	}
+
+	misdirected := NewServeMux().SetUnmatchedHostStatus(http.StatusMisdirectedRequest)
+	misdirected.HandleHost("admin.example.com", hostHandler("admin"))
+
+	res = httptest.NewRecorder()
+	misdirected.ServeHTTP(res, req)
+
+	if res.Code != http.StatusMisdirectedRequest {
+		t.Logf("expected 421, got %d", res.Code)
+		t.Fail()
+	}
+
+	misdirected = NewServeMux().SetUnmatchedHostStatus(http.StatusMisdirectedRequest)
+	misdirected.HandleHost("admin.example.com", hostHandler("admin"))
+	misdirected.Handle("/", hostHandler("paths"))
+
+	res = httptest.NewRecorder()
+	misdirected.ServeHTTP(res, req)
+
+	if res.Code != http.StatusMisdirectedRequest {
+		t.Logf("with paths: expected 421, got %d", res.Code)
+		t.Fail()
+	}
+
+	routes := mux.Routes()
+	if len(routes) != 2 || routes[0].Host != "{tenant}.example.com" || routes[1].Host != "admin.example.com" || routes[1].Path != "/users/{id}" {
+		t.Logf("unexpected routes: %+v", routes)
+		t.Fail()
+	}
+}
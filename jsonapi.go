@@ -0,0 +1,236 @@
+package rgroup
+
+import (
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSON:API media type
+const JSONAPIMediaType = "application/vnd.api+json"
+
+// JSONAPIResource is implemented by HandlerResponse data rendered as JSON:API resource objects.
+type JSONAPIResource interface {
+	JSONAPIType() string
+	JSONAPIID() string
+	JSONAPIAttributes() any
+}
+
+// JSONAPIRelated can be implemented by a JSONAPIResource to add relationships to the resource object.
+type JSONAPIRelated interface {
+	JSONAPIRelationships() map[string]JSONAPIRelationship
+}
+
+// JSONAPILinker can be implemented by a JSONAPIResource to add links to the resource object.
+type JSONAPILinker interface {
+	JSONAPILinks() map[string]string
+}
+
+// JSONAPIIdentifier is a JSON:API resource identifier object.
+type JSONAPIIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// JSONAPIRelationship is a JSON:API relationship object.
+// Data should be nil, a JSONAPIIdentifier or a []JSONAPIIdentifier.
+type JSONAPIRelationship struct {
+	Data  any               `json:"data"`
+	Links map[string]string `json:"links,omitempty"`
+	Meta  map[string]any    `json:"meta,omitempty"`
+}
+
+// JSONAPIDocument can be used as HandlerResponse data to set the included resources
+// and the top level links of the document.
+// Data should be nil, a JSONAPIResource or a slice of JSONAPIResource.
+type JSONAPIDocument struct {
+	Data     any
+	Included []JSONAPIResource
+	Links    map[string]string
+}
+
+type jsonAPIResourceObject struct {
+	Type          string                         `json:"type"`
+	ID            string                         `json:"id"`
+	Attributes    any                            `json:"attributes,omitempty"`
+	Relationships map[string]JSONAPIRelationship `json:"relationships,omitempty"`
+	Links         map[string]string              `json:"links,omitempty"`
+}
+
+type jsonAPIErrorObject struct {
+	Status string         `json:"status"`
+	Title  string         `json:"title,omitempty"`
+	Detail string         `json:"detail,omitempty"`
+	Meta   map[string]any `json:"meta,omitempty"`
+}
+
+type jsonAPIVersion struct {
+	Version string `json:"version"`
+}
+
+// jsonAPIDocument is a success document; nil primary data is rendered as null,
+// since a document must contain at least one of data, errors or meta.
+type jsonAPIDocument struct {
+	JSONAPI  jsonAPIVersion          `json:"jsonapi"`
+	Data     any                     `json:"data"`
+	Included []jsonAPIResourceObject `json:"included,omitempty"`
+	Links    map[string]string       `json:"links,omitempty"`
+	Meta     map[string]any          `json:"meta,omitempty"`
+}
+
+type jsonAPIErrorDocument struct {
+	JSONAPI jsonAPIVersion       `json:"jsonapi"`
+	Errors  []jsonAPIErrorObject `json:"errors"`
+	Meta    map[string]any       `json:"meta,omitempty"`
+}
+
+// JSONAPIBuilder returns an EnvelopeBuilder rendering responses as JSON:API documents.
+// Response data implementing JSONAPIResource (or slices of it) is rendered as resource objects,
+// any other data is set as the primary data unchanged.
+// HandlerResponse.Meta and HandlerError.Meta are set as the top level meta of the document.
+func JSONAPIBuilder() EnvelopeBuilder {
+	return jsonAPIBuilder{}
+}
+
+type jsonAPIBuilder struct{}
+
+func (jsonAPIBuilder) ContentType() string {
+	return JSONAPIMediaType
+}
+
+func (jsonAPIBuilder) FromResponse(res *HandlerResponse, forwardLogMessage bool) any {
+	doc := jsonAPIDocument{
+		JSONAPI: jsonAPIVersion{Version: "1.1"},
+		Meta:    res.Meta,
+	}
+
+	data := res.Data
+	if d, ok := data.(*JSONAPIDocument); ok && d != nil {
+		data = *d
+	}
+
+	if d, ok := data.(JSONAPIDocument); ok {
+		data = d.Data
+		doc.Links = d.Links
+		doc.Included = make([]jsonAPIResourceObject, len(d.Included))
+		for i, r := range d.Included {
+			doc.Included[i] = toJSONAPIResource(r)
+		}
+	}
+
+	doc.Data = toJSONAPIData(data)
+
+	if forwardLogMessage && res.LogMessage != "" {
+		doc.Meta = addMeta(doc.Meta, map[string]any{"message": res.LogMessage})
+	}
+
+	return &doc
+}
+
+func (jsonAPIBuilder) FromError(err *HandlerError, forwardLogMessage bool) any {
+	e := jsonAPIErrorObject{
+		Status: strconv.Itoa(err.HTTPStatus),
+		Title:  http.StatusText(err.HTTPStatus),
+		Detail: err.Response,
+	}
+
+	if forwardLogMessage && err.Error() != "" {
+		e.Meta = map[string]any{"message": err.Error()}
+	}
+
+	return &jsonAPIErrorDocument{
+		JSONAPI: jsonAPIVersion{Version: "1.1"},
+		Errors:  []jsonAPIErrorObject{e},
+		Meta:    err.Meta,
+	}
+}
+
+func toJSONAPIResource(r JSONAPIResource) jsonAPIResourceObject {
+	o := jsonAPIResourceObject{
+		Type:       r.JSONAPIType(),
+		ID:         r.JSONAPIID(),
+		Attributes: r.JSONAPIAttributes(),
+	}
+
+	if rel, ok := r.(JSONAPIRelated); ok {
+		o.Relationships = rel.JSONAPIRelationships()
+	}
+
+	if l, ok := r.(JSONAPILinker); ok {
+		o.Links = l.JSONAPILinks()
+	}
+
+	return o
+}
+
+func toJSONAPIData(data any) any {
+	if data == nil {
+		return nil
+	}
+
+	if r, ok := data.(JSONAPIResource); ok {
+		return toJSONAPIResource(r)
+	}
+
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice {
+		return data
+	}
+
+	res := make([]jsonAPIResourceObject, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		r, ok := v.Index(i).Interface().(JSONAPIResource)
+		if !ok {
+			return data
+		}
+		res[i] = toJSONAPIResource(r)
+	}
+
+	return res
+}
+
+// JSONAPINegotiation is a Middleware implementing the JSON:API content negotiation rules.
+// Requests sending JSON:API content with unsupported media type parameters are rejected with 415,
+// and requests only accepting JSON:API content with unsupported media type parameters are rejected with 406.
+func JSONAPINegotiation(h Handler) Handler {
+	return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		if ct := req.Header.Get("Content-Type"); ct != "" {
+			if t, params, err := mime.ParseMediaType(ct); err == nil && t == JSONAPIMediaType && !jsonAPIParamsAllowed(params) {
+				return nil, Error(http.StatusUnsupportedMediaType)
+			}
+		}
+
+		if accept := req.Header.Values("Accept"); len(accept) > 0 {
+			found, acceptable := false, false
+			for _, a := range strings.Split(strings.Join(accept, ","), ",") {
+				t, params, err := mime.ParseMediaType(strings.TrimSpace(a))
+				if err != nil || t != JSONAPIMediaType {
+					continue
+				}
+
+				found = true
+				if jsonAPIParamsAllowed(params) {
+					acceptable = true
+				}
+			}
+
+			if found && !acceptable {
+				return nil, Error(http.StatusNotAcceptable)
+			}
+		}
+
+		return h(w, req)
+	}
+}
+
+func jsonAPIParamsAllowed(params map[string]string) bool {
+	for k := range params {
+		if k != "ext" && k != "profile" {
+			return false
+		}
+	}
+
+	return true
+}
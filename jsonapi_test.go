@@ -0,0 +1,134 @@
+package rgroup
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testArticle struct {
+	ID     string
+	Title  string
+	Author string
+}
+
+func (a testArticle) JSONAPIType() string { return "articles" }
+func (a testArticle) JSONAPIID() string   { return a.ID }
+func (a testArticle) JSONAPIAttributes() any {
+	return map[string]string{"title": a.Title}
+}
+func (a testArticle) JSONAPIRelationships() map[string]JSONAPIRelationship {
+	return map[string]JSONAPIRelationship{
+		"author": {Data: JSONAPIIdentifier{Type: "people", ID: a.Author}},
+	}
+}
+
+type testPerson struct {
+	ID   string
+	Name string
+}
+
+func (p testPerson) JSONAPIType() string    { return "people" }
+func (p testPerson) JSONAPIID() string      { return p.ID }
+func (p testPerson) JSONAPIAttributes() any { return map[string]string{"name": p.Name} }
+func (p testPerson) JSONAPILinks() map[string]string {
+	return map[string]string{"self": "/people/" + p.ID}
+}
+
+func TestJSONAPI(t *testing.T) {
+	g := New().SetEnvelope(true).SetForwardHTTPStatus(true).SetEnvelopeBuilder(JSONAPIBuilder())
+	g.AddMiddleware(JSONAPINegotiation)
+	g.Get(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response(JSONAPIDocument{
+			Data:     []testArticle{{ID: "1", Title: "test", Author: "9"}},
+			Included: []JSONAPIResource{testPerson{ID: "9", Name: "name"}},
+			Links:    map[string]string{"self": "/articles"},
+		}).WithMeta("total", 1), nil
+	})
+	g.Post(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return nil, Error(http.StatusConflict).WithResponse("already exists")
+	})
+	h := g.Make()
+
+	t.Run("document", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", JSONAPIMediaType)
+
+		h(rr, req)
+
+		target := "{\"jsonapi\":{\"version\":\"1.1\"}," +
+			"\"data\":[{\"type\":\"articles\",\"id\":\"1\",\"attributes\":{\"title\":\"test\"},\"relationships\":{\"author\":{\"data\":{\"type\":\"people\",\"id\":\"9\"}}}}]," +
+			"\"included\":[{\"type\":\"people\",\"id\":\"9\",\"attributes\":{\"name\":\"name\"},\"links\":{\"self\":\"/people/9\"}}]," +
+			"\"links\":{\"self\":\"/articles\"},\"meta\":{\"total\":1}}"
+		if rr.Body.String() != target {
+			t.Logf("unexpected response: %s", rr.Body.String())
+			t.Fail()
+		}
+		if rr.Header().Get("Content-Type") != JSONAPIMediaType {
+			t.Logf("unexpected content type: %s", rr.Header().Get("Content-Type"))
+			t.Fail()
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+
+		h(rr, req)
+
+		if rr.Body.String() != "{\"jsonapi\":{\"version\":\"1.1\"},\"errors\":[{\"status\":\"409\",\"title\":\"Conflict\",\"detail\":\"already exists\"}]}" {
+			t.Logf("unexpected response: %s", rr.Body.String())
+			t.Fail()
+		}
+		if rr.Code != http.StatusConflict {
+			t.Logf("unexpected status: %d", rr.Code)
+			t.Fail()
+		}
+	})
+
+	t.Run("null data", func(t *testing.T) {
+		b, err := json.Marshal(JSONAPIBuilder().FromResponse(Response(nil), false))
+		if err != nil || string(b) != "{\"jsonapi\":{\"version\":\"1.1\"},\"data\":null}" {
+			t.Logf("unexpected document: %s (%v)", b, err)
+			t.Fail()
+		}
+	})
+
+	t.Run("unsupported media type", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Content-Type", JSONAPIMediaType+"; charset=utf-8")
+
+		h(rr, req)
+
+		if rr.Code != http.StatusUnsupportedMediaType {
+			t.Logf("unexpected status: %d", rr.Code)
+			t.Fail()
+		}
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", JSONAPIMediaType+"; charset=utf-8")
+
+		h(rr, req)
+
+		if rr.Code != http.StatusNotAcceptable {
+			t.Logf("unexpected status: %d", rr.Code)
+			t.Fail()
+		}
+
+		rr = httptest.NewRecorder()
+		req.Header.Add("Accept", JSONAPIMediaType+"; profile=\"https://example.com\"")
+
+		h(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Logf("unexpected status: %d", rr.Code)
+			t.Fail()
+		}
+	})
+}
@@ -13,6 +13,9 @@ type LoggerData struct {
 	Error        *HandlerError
 	Request      http.Request
 	Response     *HandlerResponse
+	Pattern      string // pattern matched by HandlerMux, including the prefixes of enclosing muxes
+	Version      string // API version resolved by the Versioning of the HandlerGroup
+	Method       string // effective method of the request, differs from Request.Method if overridden
 	err          error
 	time         bool
 	duration     int64
@@ -23,12 +26,17 @@ func fromRequest(req http.Request) *LoggerData {
 		Timestamp:    time.Now().UnixNano(),
 		Error:        nil,
 		Request:      req,
+		Method:       req.Method,
 		Response:     nil,
 		ResponseSize: 0,
 		time:         false,
 		duration:     0,
 	}
 
+	if rc := routeFrom(req.Context()); rc != nil {
+		r.Pattern = rc.pattern
+	}
+
 	return &r
 }
 
@@ -86,9 +94,14 @@ func (r *LoggerData) String() string {
 		i++
 	}
 
+	method := r.Request.Method
+	if r.Method != "" && r.Method != method {
+		method = fmt.Sprintf("%s(%s)", r.Method, method)
+	}
+
 	if r.Message() != "" {
-		return fmt.Sprintf("%s %d %s [%3.1f%s]\n%s", r.Request.Method, r.Status(), r.Path(), dur, units[i], r.Message())
+		return fmt.Sprintf("%s %d %s [%3.1f%s]\n%s", method, r.Status(), r.Path(), dur, units[i], r.Message())
 	}
 
-	return fmt.Sprintf("%s %d %s [%3.1f%s]", r.Request.Method, r.Status(), r.Path(), dur, units[i])
+	return fmt.Sprintf("%s %d %s [%3.1f%s]", method, r.Status(), r.Path(), dur, units[i])
 }
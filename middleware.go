@@ -0,0 +1,257 @@
+package rgroup
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// FromHTTPMiddleware adapts a standard net/http middleware, e.g. for CORS, authentication or tracing,
+// to a Middleware.
+//
+// The response of the next Handler is written through the http.ResponseWriter the middleware passes
+// to its next handler, before the middleware returns, so middleware wrapping the writer, e.g. for
+// tracing, status capture or compression, sees the status, headers and body.
+// As a consequence, Middleware running outside of it (added after it) can no longer change the response.
+// Responses written by the middleware without calling the next handler, e.g. CORS preflight
+// responses or authentication failures, are sent to the client as written.
+func FromHTTPMiddleware(mw func(http.Handler) http.Handler) Middleware {
+	return func(next Handler) Handler {
+		return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			var res *HandlerResponse
+			var err error
+			var called bool
+
+			rw := &recordingWriter{ResponseWriter: w}
+			mw(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				called = true
+				res, err = writeThrough(w, req, next)
+			})).ServeHTTP(rw.writer(), req)
+
+			if called || rw.status == 0 {
+				return res, err
+			}
+
+			res = Response(nil).WithHTTPStatus(rw.status).Raw()
+			res.written = true
+			res.size = rw.size
+
+			return res, nil
+		}
+	}
+}
+
+// writeThrough calls next and writes its result to w with the config of the HandlerGroup or Handler serving the request.
+// The returned response or error is a copy marked as written, to be logged but not written again.
+func writeThrough(w http.ResponseWriter, req *http.Request, next Handler) (*HandlerResponse, error) {
+	res, err := next(w, req)
+
+	l, c := fromRequest(*req), loadConfig()
+	if rc := routeFrom(req.Context()); rc != nil && rc.log != nil {
+		wl := *rc.log
+		l, c = &wl, rc.config
+	}
+
+	l.Response, l.err = res, err
+	writeResult(w, l, c)
+
+	if l.Error != nil {
+		e := *l.Error
+		e.written, e.size = true, l.ResponseSize
+
+		return nil, &e
+	}
+
+	written := Response(nil)
+	if l.Response != nil {
+		written = new(HandlerResponse)
+		*written = *l.Response
+	}
+	written.written, written.size = true, l.ResponseSize
+
+	return written, nil
+}
+
+// ToHTTPMiddleware adapts a Middleware to a standard net/http middleware usable outside rgroup.
+//
+// The next http.Handler writes directly to the client. If the Middleware returns without calling it,
+// the returned HandlerResponse or error is written according to the global Config, without
+// calling the logger.
+func ToHTTPMiddleware(m Middleware) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		f := m(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			next.ServeHTTP(w, req)
+			return nil, nil
+		})
+
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			rw := &recordingWriter{ResponseWriter: w}
+			l := fromRequest(*req)
+
+			l.Response, l.err = f(rw.writer(), req)
+
+			if rw.status == 0 {
+				writeResult(rw, l, loadConfig())
+			}
+		})
+	}
+}
+
+// Condition reports whether a conditional Middleware should run for the request.
+type Condition func(req *http.Request) bool
+
+// When returns a Middleware running the given middleware only for requests matching the condition.
+// Other requests are passed to the next Handler directly.
+func When(c Condition, m ...Middleware) Middleware {
+	return func(next Handler) Handler {
+		wrapped := next.applyMiddleware(m)
+
+		return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			if c(req) {
+				return wrapped(w, req)
+			}
+
+			return next(w, req)
+		}
+	}
+}
+
+// IfMethod matches requests with one of the given methods.
+func IfMethod(methods ...string) Condition {
+	set := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		set[strings.ToUpper(m)] = true
+	}
+
+	return func(req *http.Request) bool {
+		return set[req.Method]
+	}
+}
+
+// IfPathPrefix matches requests with a path starting with prefix.
+// The path includes the prefixes stripped by the enclosing muxes.
+func IfPathPrefix(prefix string) Condition {
+	return func(req *http.Request) bool {
+		return strings.HasPrefix(routeBase(req)+req.URL.Path, prefix)
+	}
+}
+
+// IfHeader matches requests with the header set to value, or with the header present if value is empty.
+func IfHeader(header string, value string) Condition {
+	return func(req *http.Request) bool {
+		if value == "" {
+			return len(req.Header.Values(header)) > 0
+		}
+
+		return req.Header.Get(header) == value
+	}
+}
+
+// AddNamedMiddleware appends a Middleware that can be skipped by name with Skip and SkipFor.
+// The name is also reported by HandlerMux.Routes.
+func (h *HandlerGroup) AddNamedMiddleware(name string, m Middleware) *HandlerGroup {
+	if name == "" {
+		h.fail(fmt.Errorf("empty middleware name"))
+		return h
+	}
+
+	n := len(h.middleware)
+	h.AddMiddleware(m)
+
+	if len(h.middleware) > n {
+		h.mu.Lock()
+		h.tags[len(h.tags)-1] = name
+		h.changed()
+		h.mu.Unlock()
+	}
+
+	return h
+}
+
+// AddNamedMiddleware appends a Middleware that can be skipped by name by the handler groups in mux.
+// The name is also reported by HandlerMux.Routes.
+func (m *HandlerMux) AddNamedMiddleware(name string, mid Middleware) *HandlerMux {
+	switch {
+	case name == "":
+		m.fail(fmt.Errorf("empty middleware name"))
+		return m
+	case mid == nil:
+		m.fail(fmt.Errorf("nil middleware %s", name))
+		return m
+	}
+
+	n := len(m.middleware)
+	m.AddMiddleware(mid)
+
+	if len(m.middleware) > n {
+		m.tags[len(m.tags)-1] = name
+	}
+
+	return m
+}
+
+// Skip the named middleware of the HandlerGroup and the enclosing muxes for all methods.
+func (h *HandlerGroup) Skip(names ...string) *HandlerGroup {
+	return h.SkipFor("", names...)
+}
+
+// SkipFor skips the named middleware of the HandlerGroup and the enclosing muxes for method.
+func (h *HandlerGroup) SkipFor(method string, names ...string) *HandlerGroup {
+	if h.locked() {
+		h.fail(fmt.Errorf("middleware skipped after Make"))
+		return h
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.skip == nil {
+		h.skip = make(map[string]map[string]bool)
+	}
+
+	method = strings.ToUpper(method)
+	if h.skip[method] == nil {
+		h.skip[method] = make(map[string]bool)
+	}
+
+	for _, n := range names {
+		h.skip[method][n] = true
+	}
+	h.changed()
+
+	return h
+}
+
+// skipped returns the names of the middleware skipped for method.
+func (h *HandlerGroup) skipped(method string) map[string]bool {
+	if len(h.skip[method]) == 0 {
+		return h.skip[""]
+	}
+
+	skip := make(map[string]bool, len(h.skip[""])+len(h.skip[method]))
+	for n := range h.skip[""] {
+		skip[n] = true
+	}
+	for n := range h.skip[method] {
+		skip[n] = true
+	}
+
+	return skip
+}
+
+// skipMiddleware returns the middleware whose name is not in skip.
+func skipMiddleware(middleware []Middleware, tags []string, skip map[string]bool) []Middleware {
+	if len(skip) == 0 {
+		return middleware
+	}
+
+	mid := make([]Middleware, 0, len(middleware))
+	for i, m := range middleware {
+		if i < len(tags) && skip[tags[i]] {
+			continue
+		}
+		mid = append(mid, m)
+	}
+
+	return mid
+}
@@ -0,0 +1,402 @@
+package rgroup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testContextKey struct{}
+
+func TestFromHTTPMiddleware(t *testing.T) {
+	Config.Reset()
+	defer Config.Reset()
+	Config.Envelope.Enable()
+
+	var logged *LoggerData
+	Config.SetGlobalLogger(func(l *LoggerData) { logged = l })
+
+	cors := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+
+			if req.Header.Get("Origin") == "https://blocked.example.com" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte("forbidden"))
+				return
+			}
+
+			next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), testContextKey{}, "value")))
+		})
+	}
+
+	var res *HandlerResponse
+	var err error
+	handler := func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		res = Response(req.Context().Value(testContextKey{})).WithMeta("key", "meta")
+		return res, nil
+	}
+
+	var seen *HandlerResponse
+	g := NewWithHandlers(HandlerMap{http.MethodGet: handler})
+	g.AddMiddleware(func(next Handler) Handler {
+		return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			seen, err = next(w, req)
+			return seen, err
+		}
+	}, FromHTTPMiddleware(cors))
+
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	switch {
+	case seen != res || err != nil:
+		t.Logf("response not preserved: %v %v", seen, err)
+		t.Fail()
+	case rec.Header().Get("Access-Control-Allow-Origin") != "*" || !strings.Contains(rec.Body.String(), `"data":"value"`):
+		t.Logf("unexpected response %v %s", rec.Header(), rec.Body.String())
+		t.Fail()
+	}
+
+	// rejected by the standard middleware
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://blocked.example.com")
+	rec = httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden || rec.Body.String() != "forbidden" || logged.Status() != http.StatusForbidden || logged.ResponseSize != 9 {
+		t.Logf("unexpected response %d %s", rec.Code, rec.Body.String())
+		t.Fail()
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+
+	n, err := r.ResponseWriter.Write(b)
+	r.size += n
+
+	return n, err
+}
+
+func TestFromHTTPMiddlewareWriter(t *testing.T) {
+	Config.Reset()
+	defer Config.Reset()
+
+	var logged *LoggerData
+	Config.SetGlobalLogger(func(l *LoggerData) { logged = l })
+
+	var recorded *statusRecorder
+	tracing := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			recorded = &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorded, req)
+		})
+	}
+
+	g := NewWithHandlers(HandlerMap{
+		http.MethodGet: func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return Response("created").WithHTTPStatus(http.StatusCreated).WithHeader("X-Test", "1"), nil
+		},
+		http.MethodPost: func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return nil, Error(http.StatusConflict).WithResponse("conflict")
+		},
+	}).SetEnvelope(true).SetForwardHTTPStatus(true).AddMiddleware(FromHTTPMiddleware(tracing))
+
+	mux := NewServeMux()
+	mux.Handle("/g", g)
+
+	for _, h := range []http.Handler{g, mux} {
+		for method, status := range map[string]int{http.MethodGet: http.StatusCreated, http.MethodPost: http.StatusConflict} {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(method, "/g", nil))
+
+			switch {
+			case recorded.status != status || recorded.size != rec.Body.Len() || rec.Code != status:
+				t.Logf("%s: status %d and size %d not seen by the middleware (%d %d)", method, rec.Code, rec.Body.Len(), recorded.status, recorded.size)
+				t.Fail()
+			case !strings.Contains(rec.Body.String(), `"status":{"http_status"`):
+				t.Logf("%s: group options not applied: %s", method, rec.Body.String())
+				t.Fail()
+			case logged.Status() != status || logged.ResponseSize != rec.Body.Len():
+				t.Logf("%s: unexpected log %d %d", method, logged.Status(), logged.ResponseSize)
+				t.Fail()
+			case method == http.MethodGet && rec.Header().Get("X-Test") != "1":
+				t.Logf("%s: missing header", method)
+				t.Fail()
+			}
+		}
+	}
+
+	t.Run("handlers", func(t *testing.T) {
+		identity := func(next http.Handler) http.Handler { return next }
+
+		mux := NewServeMux().SetEnvelope(true).AddMiddleware(FromHTTPMiddleware(identity))
+		mux.HandleFunc("/plain", func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return Response("x"), nil
+		})
+
+		for path, status := range map[string]string{"/plain": `{"http_status":200}`, "/missing": `{"http_status":404`} {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+			if !strings.Contains(rec.Body.String(), `"status":`+status) {
+				t.Logf("%s: mux options not applied: %s", path, rec.Body.String())
+				t.Fail()
+			}
+		}
+	})
+}
+
+func TestToHTTPMiddleware(t *testing.T) {
+	Config.Reset()
+	defer Config.Reset()
+
+	var logged bool
+	Config.SetGlobalLogger(func(l *LoggerData) { logged = true })
+
+	auth := func(next Handler) Handler {
+		return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			if req.Header.Get("Authorization") == "" {
+				return nil, Error(http.StatusUnauthorized).WithResponse("missing token")
+			}
+
+			w.Header().Set("X-User", "user")
+			return next(w, req)
+		}
+	}
+
+	h := ToHTTPMiddleware(auth)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusUnauthorized || rec.Body.String() != "missing token" {
+		t.Logf("unexpected response %d %s", rec.Code, rec.Body.String())
+		t.Fail()
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "token")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted || rec.Body.String() != "ok" || rec.Header().Get("X-User") != "user" {
+		t.Logf("unexpected response %d %s %v", rec.Code, rec.Body.String(), rec.Header())
+		t.Fail()
+	}
+
+	if logged {
+		t.Logf("logger should not be called")
+		t.Fail()
+	}
+}
+
+func TestPreMiddleware(t *testing.T) {
+	Config.Reset()
+	defer Config.Reset()
+
+	var logged []int
+	Config.SetGlobalLogger(func(l *LoggerData) { logged = append(logged, l.Status()) })
+
+	var seen []string
+	record := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+				seen = append(seen, name)
+				w.Header().Set("X-Request-ID", "id")
+				return next(w, req)
+			}
+		}
+	}
+
+	auth := func(next Handler) Handler {
+		return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			if req.Header.Get("Authorization") == "" {
+				return nil, Error(http.StatusUnauthorized)
+			}
+			return next(w, req)
+		}
+	}
+
+	g := NewWithHandlers(HandlerMap{
+		http.MethodGet: func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return Response("ok"), nil
+		},
+	}).AddPreMiddleware(record("group")).AddMiddleware(record("handler"))
+
+	var status int
+	inspect := func(next Handler) Handler {
+		return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			res, err := next(w, req)
+			if err == nil {
+				status = res.HTTPStatus
+				res.WithHeader("X-Ignored", "1")
+			}
+			return res, err
+		}
+	}
+
+	mux := NewServeMux().AddPreMiddleware(inspect, record("mux"), auth)
+	mux.Handle("/g", g)
+
+	tests := []struct {
+		method string
+		path   string
+		auth   bool
+		status int
+		seen   string
+	}{
+		{method: http.MethodGet, path: "/g", auth: true, status: http.StatusOK, seen: "mux,group,handler"},
+		{method: http.MethodOptions, path: "/g", auth: true, status: http.StatusOK, seen: "mux,group"},
+		{method: http.MethodPost, path: "/g", auth: true, status: http.StatusMethodNotAllowed, seen: "mux,group"},
+		{method: http.MethodGet, path: "/missing", auth: true, status: http.StatusNotFound, seen: "mux"},
+		{method: http.MethodGet, path: "/g", status: http.StatusUnauthorized, seen: ""},
+	}
+
+	for _, tt := range tests {
+		seen, logged, status = nil, nil, 0
+
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.auth {
+			req.Header.Set("Authorization", "token")
+		}
+
+		res := httptest.NewRecorder()
+		mux.ServeHTTP(res, req)
+
+		switch {
+		case res.Code != tt.status || len(logged) != 1 || logged[0] != tt.status:
+			t.Logf("%s %s: unexpected response %d, logged %v", tt.method, tt.path, res.Code, logged)
+			t.Fail()
+		case strings.Join(seen, ",") != tt.seen:
+			t.Logf("%s %s: unexpected middleware %v", tt.method, tt.path, seen)
+			t.Fail()
+		case tt.seen != "" && res.Header().Get("X-Request-ID") != "id":
+			t.Logf("%s %s: missing header", tt.method, tt.path)
+			t.Fail()
+		case tt.status != http.StatusUnauthorized && status != tt.status:
+			t.Logf("%s %s: pre-middleware saw status %d", tt.method, tt.path, status)
+			t.Fail()
+		}
+	}
+
+	r := mux.Routes()
+	if len(r) != 1 || len(r[0].PreMiddleware) != 4 || len(r[0].Middleware) != 1 {
+		t.Logf("unexpected routes %+v", r)
+		t.Fail()
+	}
+}
+
+func TestConditionalMiddleware(t *testing.T) {
+	Config.Reset()
+	defer Config.Reset()
+
+	var seen []string
+	record := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+				seen = append(seen, name)
+				return next(w, req)
+			}
+		}
+	}
+
+	ok := func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response("ok"), nil
+	}
+
+	items := NewWithHandlers(HandlerMap{
+		http.MethodGet:  ok,
+		http.MethodPost: ok,
+		http.MethodPut:  ok,
+	}).
+		AddMiddleware(When(IfMethod(http.MethodPost, http.MethodPut), record("csrf"))).
+		AddMiddleware(When(IfMethod(http.MethodGet), record("cache"))).
+		AddNamedMiddleware("validate", record("validate")).
+		SkipFor(http.MethodPut, "validate", "auth")
+
+	health := NewWithHandlers(HandlerMap{http.MethodGet: ok}).Skip("auth")
+
+	mux := NewServeMux().SetPrefix("/api").
+		AddNamedMiddleware("auth", record("auth")).
+		AddMiddleware(When(IfHeader("X-Debug", ""), record("debug"))).
+		AddMiddleware(When(IfPathPrefix("/api/admin"), record("admin")))
+	mux.Handle("/items", items)
+	mux.Handle("/health", health)
+
+	admin := NewServeMux().SetPrefix("/admin")
+	admin.Handle("/stats", NewWithHandlers(HandlerMap{http.MethodGet: ok}))
+	mux.Handle("/admin/", admin)
+
+	api := NewServeMux()
+	api.Handle("/api/", mux)
+
+	tests := []struct {
+		method string
+		path   string
+		debug  bool
+		seen   string
+	}{
+		{method: http.MethodGet, path: "/api/items", seen: "auth,validate,cache"},
+		{method: http.MethodPost, path: "/api/items", seen: "auth,validate,csrf"},
+		{method: http.MethodPut, path: "/api/items", seen: "csrf"},
+		{method: http.MethodGet, path: "/api/health", debug: true, seen: "debug"},
+		{method: http.MethodGet, path: "/api/admin/stats", seen: "admin,auth"},
+	}
+
+	for _, tt := range tests {
+		seen = nil
+
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.debug {
+			req.Header.Set("X-Debug", "1")
+		}
+
+		res := httptest.NewRecorder()
+		api.ServeHTTP(res, req)
+
+		if res.Code != http.StatusOK || strings.Join(seen, ",") != tt.seen {
+			t.Logf("%s %s: unexpected response %d, middleware %v", tt.method, tt.path, res.Code, seen)
+			t.Fail()
+		}
+	}
+
+	for _, r := range api.Routes() {
+		switch r.Path {
+		case "/api/items":
+			if len(r.Middleware) != 6 || r.Middleware[2] != "auth" || r.Middleware[3] != "validate" {
+				t.Logf("unexpected middleware %v", r.Middleware)
+				t.Fail()
+			}
+		case "/api/health":
+			if len(r.Middleware) != 2 {
+				t.Logf("unexpected middleware %v", r.Middleware)
+				t.Fail()
+			}
+		}
+	}
+
+	if err := New().AddNamedMiddleware("", record("x")).Validate(); err == nil {
+		t.Log("expected error for empty middleware name")
+		t.Fail()
+	}
+}
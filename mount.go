@@ -0,0 +1,200 @@
+package rgroup
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+)
+
+// passThrough is a plain http.Handler mounted with HandlerMux.Mount.
+type passThrough struct {
+	h http.Handler
+}
+
+func (p passThrough) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	p.h.ServeHTTP(w, req)
+}
+
+// Mount a plain http.Handler writing directly to the client, e.g. pprof, httputil.ReverseProxy or http.FileServer.
+// Unlike Handle, the response is not buffered or converted: headers, status codes, streaming,
+// http.Flusher and http.Hijacker are passed through when supported by the http.ResponseWriter.
+// The middleware of the mux runs around the handler and can still reject the request by returning
+// a response or an error without calling it. The status and size of the response are recorded in LoggerData.
+func (m *HandlerMux) Mount(path string, h http.Handler) {
+	m.update(func() {
+		if method, _ := splitPattern(path); method != "" {
+			m.fail(fmt.Errorf("%s: method patterns can not be mounted", path))
+			return
+		}
+
+		if h == nil {
+			m.fail(fmt.Errorf("nil handler for %s", path))
+			return
+		}
+
+		m.handle(path, passThrough{h})
+	})
+}
+
+// build generates the http.Handler of the mounted handler with the middleware and options of ctx.
+func (p passThrough) build(ctx buildContext) http.Handler {
+	return wrapHTTP(p.h, ctx.middleware, ctx.options, true)
+}
+
+// wrapHTTP applies middleware to a http.Handler writing directly to the client.
+// If the middleware returns without a response being written, the returned response or error
+// is logged and written. Otherwise the request is only logged if logWritten is set.
+func wrapHTTP(h http.Handler, middleware []Middleware, options Options, logWritten bool) http.Handler {
+	// the chain is built once; the written response returned by the handler marks it as called
+	f := Handler(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return serveWritten(h, w, req), nil
+	}).applyMiddleware(middleware)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		l := fromRequest(*req)
+		rw := &recordingWriter{ResponseWriter: w}
+
+		l.Response, l.err = f(rw.writer(), req)
+		c := options.apply(*loadConfig())
+
+		switch {
+		case rw.status == 0 && (l.Response == nil || !l.Response.written):
+			// the middleware returned without calling the handler
+			logAndWrite(rw, l, nil, c)
+			return
+		case !logWritten:
+			return
+		case rw.status == 0:
+			// the handler did not write a response
+			logAndWrite(rw, l, nil, c)
+			return
+		}
+
+		l.Response = Response(nil).WithHTTPStatus(rw.status).Raw()
+		l.ResponseSize = rw.size
+		l.err = nil
+
+		logRequest(l, nil, c)
+	})
+}
+
+// serveWritten serves req with h and returns a HandlerResponse describing what h wrote.
+// The response is marked as written, so it is logged but not written again; changing it has no effect.
+func serveWritten(h http.Handler, w http.ResponseWriter, req *http.Request) *HandlerResponse {
+	rw := &recordingWriter{ResponseWriter: w}
+	h.ServeHTTP(rw.writer(), req)
+
+	status := rw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	res := Response(nil).WithHTTPStatus(status).Raw()
+	for k := range w.Header() {
+		res.Headers[k] = w.Header().Get(k)
+	}
+	res.written = true
+	res.size = rw.size
+
+	return res
+}
+
+// recordingWriter passes writes through to the http.ResponseWriter and records the status and size of the response.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (r *recordingWriter) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recordingWriter) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+
+	n, err := r.ResponseWriter.Write(b)
+	r.size += n
+
+	return n, err
+}
+
+// ReadFrom allows the underlying http.ResponseWriter to use sendfile when serving files.
+func (r *recordingWriter) ReadFrom(src io.Reader) (int64, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+
+	var n int64
+	var err error
+	if rf, ok := r.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(struct{ io.Writer }{r.ResponseWriter}, src)
+	}
+	r.size += int(n)
+
+	return n, err
+}
+
+func (r *recordingWriter) flush() {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+
+	r.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (r *recordingWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := r.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil && r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+
+	return conn, rw, err
+}
+
+// writer returns the recordingWriter implementing http.Flusher and http.Hijacker
+// only if the underlying http.ResponseWriter does.
+func (r *recordingWriter) writer() http.ResponseWriter {
+	_, flusher := r.ResponseWriter.(http.Flusher)
+	_, hijacker := r.ResponseWriter.(http.Hijacker)
+
+	switch {
+	case flusher && hijacker:
+		return flushHijackRecorder{r}
+	case flusher:
+		return flushRecorder{r}
+	case hijacker:
+		return hijackRecorder{r}
+	default:
+		return r
+	}
+}
+
+type flushRecorder struct{ *recordingWriter }
+
+func (r flushRecorder) Flush() { r.flush() }
+
+type hijackRecorder struct{ *recordingWriter }
+
+func (r hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) { return r.hijack() }
+
+type flushHijackRecorder struct{ *recordingWriter }
+
+func (r flushHijackRecorder) Flush() { r.flush() }
+
+func (r flushHijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) { return r.hijack() }
+
+// Unwrap returns the underlying http.ResponseWriter, used by http.ResponseController.
+func (r *recordingWriter) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,129 @@
+package rgroup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMount(t *testing.T) {
+	Config.Reset()
+	defer Config.Reset()
+	Config.Envelope.Enable()
+
+	var logged *LoggerData
+	Config.SetGlobalLogger(func(l *LoggerData) { logged = l })
+
+	foreign := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Foreign", "1")
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte("a"))
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte("b"))
+	})
+
+	mux := NewServeMux().AddMiddleware(func(next Handler) Handler {
+		return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			if req.Header.Get("Authorization") == "" {
+				return nil, Error(http.StatusUnauthorized)
+			}
+			return next(w, req)
+		}
+	})
+	mux.Mount("/foreign/", foreign)
+	mux.Mount("GET /x", foreign)
+
+	if err := mux.Validate(); err == nil {
+		t.Logf("expected validation error for method pattern")
+		t.Fail()
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/foreign/x", nil)
+	req.Header.Set("Authorization", "token")
+	res := httptest.NewRecorder()
+	mux.ServeHTTP(res, req)
+
+	switch {
+	case res.Code != http.StatusTeapot || res.Body.String() != "ab" || res.Header().Get("X-Foreign") != "1":
+		t.Logf("unexpected response %d %s %v", res.Code, res.Body.String(), res.Header())
+		t.Fail()
+	case !res.Flushed:
+		t.Logf("expected response to be flushed")
+		t.Fail()
+	case logged.Status() != http.StatusTeapot || logged.ResponseSize != 2 || logged.Error != nil:
+		t.Logf("unexpected log data: %d %d %v", logged.Status(), logged.ResponseSize, logged.Error)
+		t.Fail()
+	}
+
+	// rejected by middleware, written by rgroup
+	res = httptest.NewRecorder()
+	mux.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/foreign/x", nil))
+
+	if !strings.Contains(res.Body.String(), `"http_status":401`) || logged.Status() != http.StatusUnauthorized {
+		t.Logf("unexpected response %d %s", res.Code, res.Body.String())
+		t.Fail()
+	}
+}
+
+func TestMountHijack(t *testing.T) {
+	Config.Reset()
+	defer Config.Reset()
+
+	status := make(chan int, 1)
+	Config.SetGlobalLogger(func(l *LoggerData) { status <- l.Status() })
+
+	mux := NewServeMux()
+	mux.Mount("/ws", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack failed: %s", err)
+			return
+		}
+		defer conn.Close()
+
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")
+		_ = rw.Flush()
+	}))
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/ws")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Logf("unexpected response %d", res.StatusCode)
+		t.Fail()
+	}
+
+	if s := <-status; s != http.StatusSwitchingProtocols {
+		t.Logf("expected logged status 101, got %d", s)
+		t.Fail()
+	}
+}
+
+func TestRecordingWriterInterfaces(t *testing.T) {
+	w := (&recordingWriter{ResponseWriter: httptest.NewRecorder()}).writer()
+
+	if _, ok := w.(http.Hijacker); ok {
+		t.Log("unexpected http.Hijacker")
+		t.Fail()
+	}
+
+	f, ok := w.(http.Flusher)
+	if !ok {
+		t.Fatal("expected http.Flusher")
+	}
+
+	f.Flush()
+
+	w = (&recordingWriter{ResponseWriter: struct{ http.ResponseWriter }{httptest.NewRecorder()}}).writer()
+	if _, ok := w.(http.Flusher); ok {
+		t.Log("unexpected http.Flusher")
+		t.Fail()
+	}
+}
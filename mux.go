@@ -1,14 +1,43 @@
 package rgroup
 
 import (
+	"fmt"
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type HandlerMux struct {
-	s          *http.ServeMux
+	handler    http.Handler
+	built      bool
 	h          map[string]http.Handler
 	middleware []Middleware
 	prefix     string
+	options    Options
+	errs       []error
+	methods    map[string]*HandlerGroup
+	metadata   map[string]any
+	names      map[string]string
+	hosts      []hostRoute
+	// status of the error returned for unmatched hosts
+	hostStatus int
+	notFound   Handler
+
+	trailingSlash   TrailingSlash
+	mergeSlashes    *bool
+	caseInsensitive *bool
+
+	pre []Middleware
+	// names of the middleware, empty for unnamed middleware
+	tags    []string
+	hooks   []hook
+	dynamic bool
+	// guards the routes of a dynamic mux
+	mu     sync.RWMutex
+	table  atomic.Value
+	parent buildContext
 }
 
 // Create a new empty HandlerMux
@@ -16,6 +45,7 @@ func NewServeMux() *HandlerMux {
 	h := new(HandlerMux)
 	h.h = make(map[string]http.Handler)
 	h.middleware = make([]Middleware, 0)
+	h.methods = make(map[string]*HandlerGroup)
 
 	return h
 }
@@ -25,40 +55,495 @@ func (m *HandlerMux) SetPrefix(prefix string) *HandlerMux {
 	return m
 }
 
-// Add HandlerGroup
+// paramRoute is a route registered with a pattern containing path parameters.
+type paramRoute struct {
+	pattern *pathPattern
+	h       http.Handler
+}
+
+// Add HandlerGroup.
+// Patterns follow the http.ServeMux rules and can contain path parameters
+// matching a full path segment, e.g. /users/{id}/posts/{postID}.
+// A trailing {name...} parameter matches the remainder of the path.
+// Parameters are read with PathParam, PathInt, PathUUID and PathString.
+//
+// Patterns can be qualified with a method, e.g. GET /items/{id}.
+// Method-qualified registrations for the same path are merged into a single HandlerGroup,
+// so OPTIONS and 405 responses are handled for all of them.
 func (m *HandlerMux) Handle(path string, h http.Handler) {
+	m.update(func() {
+		m.handle(path, h)
+	})
+}
+
+func (m *HandlerMux) handle(path string, h http.Handler) {
+	if m.made() && !m.dynamic {
+		m.fail(fmt.Errorf("handler for %s added after Make", path))
+		return
+	}
+
+	if method, p := splitPattern(path); method != "" {
+		m.handleMethod(method, p, h)
+		return
+	}
+
+	switch {
+	case h == nil:
+		m.fail(fmt.Errorf("nil handler for %s", path))
+		return
+	}
+
+	if hasParams(path) {
+		if _, err := parsePattern(path); err != nil {
+			m.fail(err)
+			return
+		}
+	}
+
+	if _, ok := m.h[path]; ok {
+		m.fail(fmt.Errorf("duplicate handler for %s", path))
+	}
+
 	m.h[path] = h
 }
 
+// Add Handler.
+// The pattern follows the same rules as Handle.
+func (m *HandlerMux) HandleFunc(path string, f Handler) {
+	m.update(func() {
+		if f == nil {
+			m.fail(fmt.Errorf("nil handler for %s", path))
+			return
+		}
+
+		m.handle(path, f)
+	})
+}
+
+func (m *HandlerMux) handleMethod(method string, path string, h http.Handler) {
+	var f Handler
+	switch h2 := h.(type) {
+	case nil:
+		m.fail(fmt.Errorf("nil handler for %s %s", method, path))
+		return
+	case *HandlerGroup, *HandlerMux:
+		m.fail(fmt.Errorf("%s %s: method patterns can only be used with handlers", method, path))
+		return
+	case Handler:
+		f = h2
+	default:
+		f = fromHandler(h2)
+	}
+
+	if m.methods == nil {
+		m.methods = make(map[string]*HandlerGroup)
+	}
+
+	g, ok := m.methods[path]
+	switch {
+	case !ok:
+		if _, exists := m.h[path]; exists {
+			m.fail(fmt.Errorf("duplicate handler for %s", path))
+			return
+		}
+
+		g = New()
+		m.handle(path, g)
+		if m.h[path] != g {
+			// the path was rejected
+			return
+		}
+		m.methods[path] = g
+	case g.built:
+		// groups of a built dynamic mux are replaced instead of modified
+		g = cloneGroup(g)
+		m.methods[path] = g
+		m.h[path] = g
+	}
+
+	g.AddHandler(method, f)
+}
+
+// splitPattern splits a pattern of the form "METHOD /path".
+// The method is empty if the pattern is not method-qualified.
+func splitPattern(pattern string) (string, string) {
+	i := strings.IndexAny(pattern, " \t")
+	if i < 0 {
+		return "", pattern
+	}
+
+	return strings.ToUpper(pattern[:i]), strings.TrimLeft(pattern[i+1:], " \t")
+}
+
+// fail records a registration error, or panics in strict mode.
+func (m *HandlerMux) fail(err error) {
+	if m.options.apply(lockedConfig()).strict {
+		panic(fmt.Errorf("[rgroup] %w", err))
+	}
+
+	m.errs = append(m.errs, err)
+}
+
+// paths returns the registered paths in sorted order.
+func (m *HandlerMux) paths() []string {
+	paths := make([]string, 0, len(m.h))
+	for p := range m.h {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+// Set the Options of the mux.
+// Options are copied and inherited by all handler groups and nested muxes;
+// values set on a HandlerGroup or a nested HandlerMux take precedence.
+func (m *HandlerMux) SetOptions(o *Options) *HandlerMux {
+	if o == nil {
+		o = NewOptions()
+	}
+
+	m.options = *o
+	return m
+}
+
+// Enable or disable envelope responses for all handler groups in mux.
+// Values set on a HandlerGroup or a nested HandlerMux take precedence.
+func (m *HandlerMux) SetEnvelope(enabled bool) *HandlerMux {
+	m.options.SetEnvelope(enabled)
+	return m
+}
+
+// Forward the http status code to the client for all handler groups in mux.
+// Values set on a HandlerGroup or a nested HandlerMux take precedence.
+func (m *HandlerMux) SetForwardHTTPStatus(b bool) *HandlerMux {
+	m.options.SetForwardHTTPStatus(b)
+	return m
+}
+
+// Forward the log message to the client for all handler groups in mux.
+// Values set on a HandlerGroup or a nested HandlerMux take precedence.
+func (m *HandlerMux) SetForwardLogMessage(b bool) *HandlerMux {
+	m.options.SetForwardLogMessage(b)
+	return m
+}
+
+// Set the EnvelopeBuilder used for all handler groups in mux.
+// Values set on a HandlerGroup or a nested HandlerMux take precedence.
+func (m *HandlerMux) SetEnvelopeBuilder(b EnvelopeBuilder) *HandlerMux {
+	m.options.SetEnvelopeBuilder(b)
+	return m
+}
+
+// Set the Handler serving requests not matching any route of the mux.
+// The handler is wrapped by the middleware of the mux and its response is logged and written
+// like any other response. Nested muxes inherit it unless they set their own.
+// Default: a 404 HandlerError
+func (m *HandlerMux) SetNotFound(h Handler) *HandlerMux {
+	m.notFound = h
+	return m
+}
+
+func notFoundHandler(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+	return nil, Error(http.StatusNotFound)
+}
+
 // Add middleware to all handler groups in mux
 func (m *HandlerMux) AddMiddleware(mid ...Middleware) *HandlerMux {
+	if m.made() {
+		m.fail(fmt.Errorf("middleware added after Make"))
+		return m
+	}
+
 	m.middleware = append(m.middleware, mid...)
+	m.tags = append(m.tags, make([]string, len(mid))...)
+	return m
+}
+
+// AddPreMiddleware appends Middleware wrapping the whole dispatch of the mux, including requests
+// served by the not found handler. Pre-middleware runs before routing; routes write their own response,
+// so the HandlerResponse returned by the next Handler describes the status, headers and size written
+// and changing it has no effect.
+func (m *HandlerMux) AddPreMiddleware(mid ...Middleware) *HandlerMux {
+	if m.made() {
+		m.fail(fmt.Errorf("pre-middleware added after Make"))
+		return m
+	}
+
+	for _, f := range mid {
+		if f == nil {
+			m.fail(fmt.Errorf("nil pre-middleware"))
+			return m
+		}
+	}
+
+	m.pre = append(m.pre, mid...)
 	return m
 }
 
-// Generates an http.ServeMux from the HandlerMux.
+// buildContext holds the configuration inherited from the enclosing muxes while building.
+type buildContext struct {
+	// middleware of the enclosing muxes, innermost first
+	middleware []Middleware
+	// names of middleware
+	tags     []string
+	options  Options
+	notFound Handler
+	paths    pathPolicy
+	// pre-middleware of the enclosing muxes, innermost first; only used by Routes
+	pre []Middleware
+	// hooks of the enclosing muxes, outermost first
+	hooks []hook
+}
+
+// child returns the buildContext for the routes of m.
+func (ctx buildContext) child(m *HandlerMux) buildContext {
+	mid := make([]Middleware, 0, len(m.middleware)+len(ctx.middleware))
+	mid = append(mid, m.middleware...)
+	mid = append(mid, ctx.middleware...)
+
+	tags := make([]string, 0, len(mid))
+	tags = append(tags, m.tags...)
+	tags = append(tags, ctx.tags...)
+
+	notFound := m.notFound
+	if notFound == nil {
+		notFound = ctx.notFound
+	}
+
+	pre := make([]Middleware, 0, len(m.pre)+len(ctx.pre))
+	pre = append(pre, m.pre...)
+	pre = append(pre, ctx.pre...)
+
+	hooks := make([]hook, 0, len(ctx.hooks)+len(m.hooks))
+	hooks = append(hooks, ctx.hooks...)
+	hooks = append(hooks, m.hooks...)
+
+	return buildContext{
+		middleware: mid,
+		hooks:      hooks,
+		tags:       tags,
+		pre:        pre,
+		options:    m.options.inherit(ctx.options),
+		notFound:   notFound,
+		paths:      ctx.paths.child(m),
+	}
+}
+
+// made reports whether the mux has been built.
+func (m *HandlerMux) made() bool {
+	return m.handler != nil || m.built
+}
+
+// Generates an http.Handler from the HandlerMux.
+//
+// Handler groups and nested muxes inherit the middleware and options of the enclosing muxes
+// without being modified, so they can be added to more than one mux.
+// Middleware is applied from the inside out: the middleware of the HandlerGroup wraps the Handler,
+// followed by the middleware of each enclosing mux from the innermost to the outermost.
+// Within each level, middleware added later wraps middleware added earlier and therefore runs first.
 func (m *HandlerMux) Make() http.Handler {
-	if m.s != nil {
-		return m.s
-	}
-
-	m.s = new(http.ServeMux)
-
-	for p, h := range m.h {
-		var h3 http.Handler
-		switch h2 := h.(type) {
-		case *HandlerMux:
-			h2.AddMiddleware(m.middleware...)
-			h3 = h2.Make()
-		case *HandlerGroup:
-			h2.AddMiddleware(m.middleware...)
-			h3 = h2.Make()
-		default:
-			h3 = fromHandler(h2).applyMiddleware(m.middleware).ToHandlerFunc()
+	if m.handler != nil {
+		return m.handler
+	}
+
+	m.handler = m.build(buildContext{})
+
+	return m.handler
+}
+
+// build generates an http.Handler from the HandlerMux with the middleware and options
+// inherited from the enclosing muxes.
+func (m *HandlerMux) build(parent buildContext) http.Handler {
+	var h http.Handler
+	if m.dynamic {
+		h = m.buildDynamic(parent)
+	} else {
+		m.built = true
+		h = m.buildTable(parent)
+	}
+
+	if len(m.pre) == 0 {
+		return h
+	}
+
+	return wrapHTTP(h, m.pre, parent.child(m).options, false)
+}
+
+// buildTable generates the route table of the HandlerMux.
+func (m *HandlerMux) buildTable(parent buildContext) *muxTable {
+	ctx := parent.child(m)
+	t := &muxTable{
+		s:      new(http.ServeMux),
+		static: make(map[string]http.Handler),
+		routes: make([]paramRoute, 0),
+		prefix: m.prefix,
+		paths:  ctx.paths,
+	}
+
+	for _, p := range m.paths() {
+		h := buildRoute(ctx, m.h[p])
+
+		if hasParams(p) {
+			pp, _ := parsePattern(p)
+			t.routes = append(t.routes, paramRoute{pattern: pp, h: h})
+			continue
+		}
+
+		t.s.Handle(p, h)
+		t.static[p] = h
+	}
+
+	sort.SliceStable(t.routes, func(i, j int) bool {
+		return t.routes[i].pattern.moreSpecific(t.routes[j].pattern)
+	})
+
+	notFound := ctx.notFound
+	if notFound == nil {
+		notFound = notFoundHandler
+	}
+	t.notFound = buildRoute(ctx, notFound)
+
+	m.buildHosts(ctx, t)
+
+	return t
+}
+
+// buildRoute generates the http.Handler of a route with the middleware and options of ctx.
+func buildRoute(ctx buildContext, h http.Handler) http.Handler {
+	switch h2 := h.(type) {
+	case *HandlerMux:
+		return h2.build(ctx)
+	case *HandlerGroup:
+		h2.built, h2.parent = true, ctx.options
+		return h2.build(ctx)
+	case passThrough:
+		return h2.build(ctx)
+	case Handler:
+		return h2.applyMiddleware(ctx.middleware).toHandlerFunc(ctx.options)
+	default:
+		return fromHandler(h2).applyMiddleware(ctx.middleware).toHandlerFunc(ctx.options)
+	}
+}
+
+// muxTable is the route table generated by HandlerMux.build
+type muxTable struct {
+	s      *http.ServeMux
+	static map[string]http.Handler
+	routes []paramRoute
+	prefix string
+	hosts  []hostRoute
+	// handler for requests not matching any host, nil if requests fall through to the paths
+	unmatchedHost http.Handler
+	notFound      http.Handler
+	paths         pathPolicy
+}
+
+// routeMatch is the route matched by muxTable.lookup.
+type routeMatch struct {
+	h       http.Handler
+	pattern string
+	params  map[string]string
+	// path in the registered case
+	path string
+}
+
+// ServeHTTP strips the prefix of the mux from the normalized path and dispatches the request
+// by host, then by path.
+func (t *muxTable) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	path := req.URL.Path
+	if t.paths.mergeSlashes {
+		path = mergeSlashes(path)
+	}
+
+	path, ok := t.stripPrefix(path)
+	if !ok {
+		t.notFound.ServeHTTP(w, req)
+		return
+	}
+
+	base := routeBase(req) + t.prefix
+
+	if len(t.hosts) > 0 {
+		host := requestHost(req)
+		for _, r := range t.hosts {
+			if params, ok := r.pattern.match(host); ok {
+				r.h.ServeHTTP(w, withHost(withPath(req, base, path), params))
+				return
+			}
+		}
+
+		if t.unmatchedHost != nil {
+			t.unmatchedHost.ServeHTTP(w, req)
+			return
 		}
-		m.s.Handle(p, h3)
 	}
-	return http.StripPrefix(m.prefix, m.s)
+
+	m, ok := t.lookup(req, path)
+	if !ok && path != "" && path != "/" {
+		var redirect int
+		switch t.paths.trailingSlash {
+		case TrailingSlashDefault:
+			// http.ServeMux only redirects if the pattern matches case-sensitively
+			if t.paths.caseInsensitive && !strings.HasSuffix(path, "/") {
+				if m2, ok2 := t.lookup(req, path+"/"); ok2 && m2.pattern == m2.path {
+					m, redirect = m2, http.StatusMovedPermanently
+				}
+			}
+		case TrailingSlashRedirect:
+			if m2, ok2 := t.lookup(req, toggleSlash(path)); ok2 {
+				m, redirect = m2, http.StatusPermanentRedirect
+			}
+		case TrailingSlashEqual:
+			if m2, ok2 := t.lookup(req, toggleSlash(path)); ok2 {
+				m, ok = m2, true
+			}
+		}
+
+		if redirect != 0 {
+			u := *req.URL
+			u.Path, u.RawPath = base+m.path, ""
+			http.Redirect(w, req, u.String(), redirect)
+			return
+		}
+	}
+
+	req = withPath(req, base, m.path)
+	if !ok {
+		t.notFound.ServeHTTP(w, req)
+		return
+	}
+
+	m.h.ServeHTTP(w, withRoute(req, base, m.pattern, m.params))
+}
+
+// lookup returns the first matching pattern with path parameters,
+// unless a more specific pattern is matched by the http.ServeMux.
+func (t *muxTable) lookup(req *http.Request, path string) (routeMatch, bool) {
+	h, pattern, canonical := t.matchStatic(req, path)
+
+	for _, r := range t.routes {
+		params, ok := r.pattern.matchFold(path, t.paths.caseInsensitive)
+		if !ok {
+			continue
+		}
+
+		if pattern == "" || !r.pattern.shadowedBy(pattern) {
+			if t.paths.caseInsensitive {
+				path = r.pattern.canonical(path)
+			}
+
+			return routeMatch{h: r.h, pattern: r.pattern.raw, params: params, path: path}, true
+		}
+
+		break
+	}
+
+	if pattern == "" {
+		return routeMatch{path: path}, false
+	}
+
+	return routeMatch{h: h, pattern: pattern, path: canonical}, true
 }
 
 func (m *HandlerMux) ServeHTTP(w http.ResponseWriter, req *http.Request) {
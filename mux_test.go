@@ -1,8 +1,10 @@
 package rgroup
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -81,3 +83,222 @@ func TestMuxAddHandlers(t *testing.T) {
 		}
 	}
 }
+
+func TestMuxMethodPatterns(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response("GET " + PathParam(req, "id")), nil
+	})
+	mux.HandleFunc("delete /items/{id}", func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response("DELETE " + PathParam(req, "id")), nil
+	})
+	mux.Handle("PUT /items/{id}", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte("PUT"))
+	}))
+	mux.HandleFunc("GET /items", func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response("list"), nil
+	})
+
+	if err := mux.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	type testRoute struct {
+		method   string
+		path     string
+		status   int
+		response string
+	}
+
+	for _, r := range []testRoute{
+		{method: http.MethodGet, path: "/items/1", status: http.StatusOK, response: "GET 1"},
+		{method: http.MethodDelete, path: "/items/2", status: http.StatusOK, response: "DELETE 2"},
+		{method: http.MethodPut, path: "/items/3", status: http.StatusOK, response: "PUT"},
+		{method: http.MethodPost, path: "/items/4", status: http.StatusMethodNotAllowed, response: ""},
+		{method: http.MethodGet, path: "/items", status: http.StatusOK, response: "list"},
+	} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(r.method, r.path, nil))
+
+		if rr.Code != r.status || rr.Body.String() != r.response {
+			t.Logf("unexpected response for %s %s: %d %s", r.method, r.path, rr.Code, rr.Body.String())
+			t.Fail()
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodOptions, "/items/1", nil))
+	if allow := rr.Header().Get("Allow"); len(strings.Split(allow, ",")) != 4 {
+		t.Logf("unexpected Allow header: %s", allow)
+		t.Fail()
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		mux := NewServeMux()
+		mux.Handle("/items", New())
+		mux.HandleFunc("GET /items", func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return nil, nil
+		})
+		mux.Handle("GET /groups", New())
+		mux.HandleFunc("GET /nil", nil)
+
+		mux.HandleFunc("GET /bad/{id", func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return nil, nil
+		})
+		if _, ok := mux.methods["/bad/{id"]; ok {
+			t.Logf("group registered for invalid pattern")
+			t.Fail()
+		}
+
+		verr := new(ValidationError)
+		if err := mux.Validate(); !errors.As(err, &verr) || len(verr.Errors) != 4 {
+			t.Logf("unexpected error: %v", err)
+			t.Fail()
+		}
+	})
+
+	t.Run("after Make", func(t *testing.T) {
+		mux := NewServeMux()
+		mux.HandleFunc("GET /x", func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return Response("get"), nil
+		})
+		mux.Make()
+
+		mux.HandleFunc("POST /x", func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return Response("post"), nil
+		})
+		mux.HandleFunc("POST /y", func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return Response("post"), nil
+		})
+
+		verr := new(ValidationError)
+		if err := mux.Validate(); !errors.As(err, &verr) || len(verr.Errors) != 2 {
+			t.Logf("unexpected error: %v", err)
+			t.Fail()
+		}
+
+		if _, ok := mux.methods["/y"]; ok {
+			t.Logf("group registered after Make")
+			t.Fail()
+		}
+
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/x", nil))
+		if rr.Code != http.StatusMethodNotAllowed {
+			t.Logf("unexpected status %d", rr.Code)
+			t.Fail()
+		}
+	})
+}
+
+func TestMuxSharedGroup(t *testing.T) {
+	tag := func(s string) Middleware {
+		return func(h Handler) Handler {
+			return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+				res, err := h(w, req)
+				if err != nil {
+					return nil, err
+				}
+				return Response(res.Data.(string) + " " + s), nil
+			}
+		}
+	}
+
+	g := New().AddMiddleware(tag("g1"), tag("g2"))
+	g.Get(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response("test"), nil
+	})
+
+	inner := NewServeMux().SetPrefix("/inner").AddMiddleware(tag("inner"))
+	inner.Handle("/g", g)
+
+	outer := NewServeMux().AddMiddleware(tag("outer1"), tag("outer2"))
+	outer.Handle("/inner/", inner)
+	outer.Handle("/g", g)
+
+	other := NewServeMux().SetOptions(NewOptions().SetEnvelope(true)).AddMiddleware(tag("other"))
+	other.Handle("/g", g)
+
+	type testRoute struct {
+		mux      http.Handler
+		path     string
+		response string
+	}
+
+	for _, r := range []testRoute{
+		{mux: outer, path: "/inner/g", response: "test g1 g2 inner outer1 outer2"},
+		{mux: outer, path: "/g", response: "test g1 g2 outer1 outer2"},
+		{mux: other, path: "/g", response: "{\"data\":\"test g1 g2 other\",\"status\":{\"http_status\":200}}"},
+		{mux: g, path: "/", response: "test g1 g2"},
+	} {
+		rr := httptest.NewRecorder()
+		r.mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, r.path, nil))
+
+		if rr.Body.String() != r.response {
+			t.Logf("unexpected response for %s: %s", r.path, rr.Body.String())
+			t.Fail()
+		}
+	}
+
+	if len(g.middleware) != 2 || g.options.envelope.enabled != nil {
+		t.Log("HandlerGroup modified by HandlerMux")
+		t.Fail()
+	}
+}
+
+func TestMuxNotFound(t *testing.T) {
+	Config.Reset()
+	defer Config.Reset()
+
+	var logged []int
+	Config.SetGlobalLogger(func(l *LoggerData) { logged = append(logged, l.Status()) })
+
+	h := func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response("test"), nil
+	}
+
+	sub := NewServeMux().SetPrefix("/sub").SetNotFound(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return nil, Error(http.StatusNotFound).WithResponse("no such sub resource %s", req.URL.Path)
+	})
+	sub.HandleFunc("/a", h)
+
+	nested := NewServeMux().SetPrefix("/nested")
+	nested.HandleFunc("/b", h)
+
+	var ran bool
+	mux := NewServeMux().SetEnvelope(true).SetForwardHTTPStatus(true).AddMiddleware(func(next Handler) Handler {
+		return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			ran = true
+			return next(w, req)
+		}
+	})
+	mux.HandleFunc("/a", h)
+	mux.Handle("/sub/", sub)
+	mux.Handle("/nested/", nested)
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{path: "/missing", body: `"http_status":404`},
+		{path: "/sub/missing", body: "no such sub resource /missing"},
+		{path: "/nested/missing", body: `"http_status":404`},
+	}
+
+	for _, tt := range tests {
+		ran = false
+		logged = nil
+
+		res := httptest.NewRecorder()
+		mux.ServeHTTP(res, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		switch {
+		case res.Code != http.StatusNotFound || !strings.Contains(res.Body.String(), tt.body):
+			t.Logf("%s: unexpected response %d %s", tt.path, res.Code, res.Body.String())
+			t.Fail()
+		case !ran || len(logged) != 1 || logged[0] != http.StatusNotFound:
+			t.Logf("%s: middleware ran %v, logged %v", tt.path, ran, logged)
+			t.Fail()
+		}
+	}
+}
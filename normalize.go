@@ -0,0 +1,177 @@
+package rgroup
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TrailingSlash is the policy of a HandlerMux for paths differing from a route only by a trailing slash.
+type TrailingSlash int
+
+const (
+	// Follow the http.ServeMux rules: /a is redirected to /a/ if only /a/ is registered.
+	TrailingSlashDefault TrailingSlash = iota
+	// Paths must match the registered route exactly.
+	TrailingSlashStrict
+	// Redirect to the registered route with 308 Permanent Redirect.
+	TrailingSlashRedirect
+	// Serve the registered route as if it had been requested.
+	TrailingSlashEqual
+)
+
+// pathPolicy holds the path normalization policies of a HandlerMux.
+type pathPolicy struct {
+	trailingSlash   TrailingSlash
+	mergeSlashes    bool
+	caseInsensitive bool
+}
+
+// Set the trailing slash policy of the mux.
+// Nested muxes inherit the policy unless they set their own.
+// Default: TrailingSlashDefault
+func (m *HandlerMux) SetTrailingSlash(p TrailingSlash) *HandlerMux {
+	m.trailingSlash = p
+	return m
+}
+
+// Merge duplicate slashes in the request path before dispatch, e.g. /a//b is served as /a/b.
+// Nested muxes inherit the value unless they set their own.
+// Default: false
+func (m *HandlerMux) SetMergeSlashes(b bool) *HandlerMux {
+	m.mergeSlashes = &b
+	return m
+}
+
+// Match the literal parts of the routes and the prefix of the mux case-insensitively.
+// The matched parts of the path are rewritten to the registered case; path parameters are unchanged.
+// Nested muxes inherit the value unless they set their own.
+// Default: false
+func (m *HandlerMux) SetCaseInsensitive(b bool) *HandlerMux {
+	m.caseInsensitive = &b
+	return m
+}
+
+// child returns the path policy for the routes of m.
+func (p pathPolicy) child(m *HandlerMux) pathPolicy {
+	if m.trailingSlash != TrailingSlashDefault {
+		p.trailingSlash = m.trailingSlash
+	}
+
+	if m.mergeSlashes != nil {
+		p.mergeSlashes = *m.mergeSlashes
+	}
+
+	if m.caseInsensitive != nil {
+		p.caseInsensitive = *m.caseInsensitive
+	}
+
+	return p
+}
+
+// mergeSlashes replaces runs of slashes in path with a single slash.
+func mergeSlashes(path string) string {
+	if !strings.Contains(path, "//") {
+		return path
+	}
+
+	var b strings.Builder
+	b.Grow(len(path))
+
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' && i > 0 && path[i-1] == '/' {
+			continue
+		}
+		b.WriteByte(path[i])
+	}
+
+	return b.String()
+}
+
+// toggleSlash adds a trailing slash to path, or removes it if present.
+func toggleSlash(path string) string {
+	if strings.HasSuffix(path, "/") {
+		return strings.TrimSuffix(path, "/")
+	}
+
+	return path + "/"
+}
+
+// stripPrefix removes the prefix of the table from path.
+func (t *muxTable) stripPrefix(path string) (string, bool) {
+	switch {
+	case t.prefix == "":
+		return path, true
+	case strings.HasPrefix(path, t.prefix):
+		return path[len(t.prefix):], true
+	case t.paths.caseInsensitive && len(path) >= len(t.prefix) && strings.EqualFold(path[:len(t.prefix)], t.prefix):
+		return path[len(t.prefix):], true
+	default:
+		return "", false
+	}
+}
+
+// matchStatic returns the static pattern matching path and the canonical path.
+func (t *muxTable) matchStatic(req *http.Request, path string) (http.Handler, string, string) {
+	if !t.paths.caseInsensitive {
+		r := *req
+		u := *req.URL
+		u.Path, u.RawPath = path, ""
+		r.URL = &u
+
+		h, pattern := t.s.Handler(&r)
+
+		// the redirect of http.ServeMux to the pattern with a trailing slash
+		// is handled by the trailing slash policy
+		if t.paths.trailingSlash != TrailingSlashDefault && pattern == path+"/" {
+			return nil, "", path
+		}
+
+		return h, pattern, path
+	}
+
+	var h http.Handler
+	match, canonical := "", path
+	for p, ph := range t.static {
+		if len(p) <= len(match) {
+			continue
+		}
+
+		switch {
+		case strings.EqualFold(p, path):
+			canonical = p
+		case strings.HasSuffix(p, "/") && len(path) >= len(p) && strings.EqualFold(path[:len(p)], p):
+			canonical = p + path[len(p):]
+		default:
+			continue
+		}
+
+		h, match = ph, p
+	}
+
+	return h, match, canonical
+}
+
+// withPath returns req with the path set to the stripped path.
+// If the path was normalized, the RequestURI is updated so the normalized path is logged.
+func withPath(req *http.Request, base string, path string) *http.Request {
+	if path == req.URL.Path {
+		return req
+	}
+
+	r := new(http.Request)
+	*r = *req
+	r.URL = new(url.URL)
+	*r.URL = *req.URL
+	r.URL.Path = path
+	r.URL.RawPath = ""
+
+	if full := base + path; full != routeBase(req)+req.URL.Path {
+		r.RequestURI = (&url.URL{Path: full}).EscapedPath()
+		if r.URL.RawQuery != "" {
+			r.RequestURI += "?" + r.URL.RawQuery
+		}
+	}
+
+	return r
+}
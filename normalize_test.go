@@ -0,0 +1,127 @@
+package rgroup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPathNormalization(t *testing.T) {
+	Config.Reset()
+	defer Config.Reset()
+
+	var path string
+	Config.SetGlobalLogger(func(l *LoggerData) { path = l.Path() })
+
+	h := func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response(PathParam(req, "id")), nil
+	}
+
+	newMux := func() *HandlerMux {
+		sub := NewServeMux().SetPrefix("/Sub")
+		sub.HandleFunc("/items/{id}", h)
+
+		mux := NewServeMux()
+		mux.HandleFunc("/a", h)
+		mux.HandleFunc("/b/", h)
+		mux.Handle("/Sub/", sub)
+
+		return mux
+	}
+
+	type testRequest struct {
+		path     string
+		status   int
+		location string
+		logged   string
+	}
+
+	tests := []struct {
+		name string
+		mux  *HandlerMux
+		reqs []testRequest
+	}{
+		{
+			name: "default",
+			mux:  newMux(),
+			reqs: []testRequest{
+				{path: "/a/", status: http.StatusNotFound},
+				{path: "/b", status: http.StatusMovedPermanently, location: "/b/"},
+				{path: "/SUB/items/1", status: http.StatusNotFound},
+			},
+		},
+		{
+			name: "strict",
+			mux:  newMux().SetTrailingSlash(TrailingSlashStrict),
+			reqs: []testRequest{
+				{path: "/b", status: http.StatusNotFound},
+				{path: "/b/", status: http.StatusOK, logged: "/b/"},
+			},
+		},
+		{
+			name: "redirect",
+			mux:  newMux().SetTrailingSlash(TrailingSlashRedirect),
+			reqs: []testRequest{
+				{path: "/a/?x=1", status: http.StatusPermanentRedirect, location: "/a?x=1"},
+				{path: "/b", status: http.StatusPermanentRedirect, location: "/b/"},
+				{path: "/Sub/items/1/", status: http.StatusPermanentRedirect, location: "/Sub/items/1"},
+			},
+		},
+		{
+			name: "equal",
+			mux:  newMux().SetTrailingSlash(TrailingSlashEqual),
+			reqs: []testRequest{
+				{path: "/a/", status: http.StatusOK, logged: "/a"},
+				{path: "/b", status: http.StatusOK, logged: "/b/"},
+				{path: "/c/", status: http.StatusNotFound, logged: "/c/"},
+			},
+		},
+		{
+			name: "merge slashes",
+			mux:  newMux().SetMergeSlashes(true),
+			reqs: []testRequest{
+				{path: "//a", status: http.StatusOK, logged: "/a"},
+				{path: "/Sub//items///1", status: http.StatusOK, logged: "/Sub/items/1"},
+			},
+		},
+		{
+			name: "case insensitive",
+			mux:  newMux().SetCaseInsensitive(true),
+			reqs: []testRequest{
+				{path: "/A", status: http.StatusOK, logged: "/a"},
+				{path: "/SUB/ITEMS/Xy", status: http.StatusOK, logged: "/Sub/items/Xy"},
+				{path: "/B", status: http.StatusMovedPermanently, location: "/b/"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, r := range tt.reqs {
+			path = ""
+
+			res := httptest.NewRecorder()
+			tt.mux.ServeHTTP(res, httptest.NewRequest(http.MethodGet, r.path, nil))
+
+			switch {
+			case res.Code != r.status:
+				t.Logf("%s %s: expected %d, got %d", tt.name, r.path, r.status, res.Code)
+				t.Fail()
+			case r.location != "" && res.Header().Get("Location") != r.location:
+				t.Logf("%s %s: unexpected location %s", tt.name, r.path, res.Header().Get("Location"))
+				t.Fail()
+			case r.logged != "" && path != r.logged:
+				t.Logf("%s %s: unexpected logged path %s", tt.name, r.path, path)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestMergeSlashes(t *testing.T) {
+	for in, out := range map[string]string{"/": "/", "//": "/", "/a//b///c": "/a/b/c", "/a/": "/a/"} {
+		if s := mergeSlashes(in); s != out {
+			t.Logf("%s: expected %s, got %s", in, out, s)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package rgroup
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Options holds configuration attached to a HandlerGroup or HandlerMux.
+// Unset values are inherited from the parent HandlerMux and finally from the global Config.
+type Options struct {
+	logger          func(*LoggerData)
+	prewriter       func(*http.Request, *HandlerResponse) *HandlerResponse
+	logOptions      *bool
+	forwardErrorLog *bool
+	lockOnMake      *bool
+	strict          *bool
+	envelope        envelopeOverride
+	versioning      *Versioning
+	methodOverride  []string
+}
+
+// Create a new empty Options.
+func NewOptions() *Options {
+	return new(Options)
+}
+
+// Set the logger function.
+func (o *Options) SetLogger(p func(*LoggerData)) *Options {
+	if p == nil {
+		p = func(l *LoggerData) {}
+	}
+
+	o.logger = p
+
+	return o
+}
+
+// Set the prewriter function.
+func (o *Options) SetPrewriter(f func(*http.Request, *HandlerResponse) *HandlerResponse) *Options {
+	o.prewriter = f
+
+	return o
+}
+
+// Call logger function on OPTIONS requests.
+func (o *Options) SetLogOptionsRequests(b bool) *Options {
+	o.logOptions = &b
+
+	return o
+}
+
+// Send error log message to client.
+// This is only respected if envelope responses are not enabled.
+func (o *Options) SetForwardErrorLog(b bool) *Options {
+	o.forwardErrorLog = &b
+
+	return o
+}
+
+// Lock HandlerGroup after the first call to HandlerGroup.Make.
+func (o *Options) LockOnMake(b bool) *Options {
+	o.lockOnMake = &b
+
+	return o
+}
+
+// Panic on registration errors instead of recording them for Validate.
+func (o *Options) SetStrict(b bool) *Options {
+	o.strict = &b
+
+	return o
+}
+
+// Enable or disable envelope responses.
+func (o *Options) SetEnvelope(enabled bool) *Options {
+	o.envelope.enabled = &enabled
+
+	return o
+}
+
+// Forward the http status code to the client.
+func (o *Options) SetForwardHTTPStatus(b bool) *Options {
+	o.envelope.forwardHTTPStatus = &b
+
+	return o
+}
+
+// Forward the log message to the client.
+func (o *Options) SetForwardLogMessage(b bool) *Options {
+	o.envelope.forwardLogMessage = &b
+
+	return o
+}
+
+// Set the EnvelopeBuilder used to create enveloped responses.
+func (o *Options) SetEnvelopeBuilder(b EnvelopeBuilder) *Options {
+	o.envelope.builder = b
+
+	return o
+}
+
+// Set the Versioning used to select versioned handlers.
+func (o *Options) SetVersioning(v *Versioning) *Options {
+	o.versioning = v
+
+	return o
+}
+
+// Allow POST requests to override their method with one of methods.
+// Calling it without methods disables method override.
+func (o *Options) SetMethodOverride(methods ...string) *Options {
+	o.methodOverride = make([]string, len(methods))
+	for i, m := range methods {
+		o.methodOverride[i] = strings.ToUpper(m)
+	}
+
+	return o
+}
+
+// inherit fills the unset values of o from parent.
+func (o Options) inherit(parent Options) Options {
+	if o.logger == nil {
+		o.logger = parent.logger
+	}
+
+	if o.prewriter == nil {
+		o.prewriter = parent.prewriter
+	}
+
+	if o.logOptions == nil {
+		o.logOptions = parent.logOptions
+	}
+
+	if o.forwardErrorLog == nil {
+		o.forwardErrorLog = parent.forwardErrorLog
+	}
+
+	if o.lockOnMake == nil {
+		o.lockOnMake = parent.lockOnMake
+	}
+
+	if o.strict == nil {
+		o.strict = parent.strict
+	}
+
+	o.envelope = o.envelope.inherit(parent.envelope)
+
+	if o.versioning == nil {
+		o.versioning = parent.versioning
+	}
+
+	if o.methodOverride == nil {
+		o.methodOverride = parent.methodOverride
+	}
+
+	return o
+}
+
+// apply returns a copy of c with the options applied.
+func (o Options) apply(c globalConfig) *globalConfig {
+	if o.logger != nil {
+		c.logger = o.logger
+	}
+
+	if o.prewriter != nil {
+		c.prewriter = o.prewriter
+	}
+
+	if o.logOptions != nil {
+		c.logOptions = *o.logOptions
+	}
+
+	if o.forwardErrorLog != nil {
+		c.forwardErrorLog = *o.forwardErrorLog
+	}
+
+	if o.lockOnMake != nil {
+		c.lockOnMake = *o.lockOnMake
+	}
+
+	if o.strict != nil {
+		c.strict = *o.strict
+	}
+
+	return o.envelope.apply(c)
+}
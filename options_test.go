@@ -0,0 +1,91 @@
+package rgroup
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInstanceOptions(t *testing.T) {
+	handler := func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response("test").WithMessage("test message"), nil
+	}
+
+	t.Run("group", func(t *testing.T) {
+		t.Parallel()
+
+		logged := 0
+		g := New().SetOptions(NewOptions().SetEnvelope(true).SetLogger(func(ld *LoggerData) { logged++ }))
+		g.Get(handler)
+
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if rr.Body.String() != "{\"data\":\"test\",\"status\":{\"http_status\":200}}" {
+			t.Logf("unexpected response: %s", rr.Body.String())
+			t.Fail()
+		}
+		if logged != 1 {
+			t.Logf("unexpected log count: %d", logged)
+			t.Fail()
+		}
+	})
+
+	t.Run("mux", func(t *testing.T) {
+		t.Parallel()
+
+		var logged []string
+		o := NewOptions().
+			SetLogger(func(ld *LoggerData) { logged = append(logged, ld.Path()) }).
+			SetPrewriter(func(r *http.Request, hr *HandlerResponse) *HandlerResponse {
+				return Response(fmt.Sprintf("%s: prewriter", hr.Data))
+			})
+
+		g1 := New()
+		g1.Get(handler)
+		g2 := New().SetOptions(NewOptions().SetEnvelope(true))
+		g2.Get(handler)
+
+		mux := NewServeMux().SetOptions(o)
+		mux.Handle("/g1", g1)
+		mux.Handle("/g2", g2)
+
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/g1", nil))
+		if rr.Body.String() != "test: prewriter" {
+			t.Logf("unexpected response: %s", rr.Body.String())
+			t.Fail()
+		}
+
+		rr = httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/g2", nil))
+		if rr.Body.String() != "{\"data\":\"test: prewriter\",\"status\":{\"http_status\":200}}" {
+			t.Logf("unexpected response: %s", rr.Body.String())
+			t.Fail()
+		}
+
+		if len(logged) != 2 || logged[0] != "/g1" || logged[1] != "/g2" {
+			t.Logf("unexpected log: %v", logged)
+			t.Fail()
+		}
+	})
+
+	t.Run("lock on make", func(t *testing.T) {
+		t.Parallel()
+
+		g := New().SetOptions(NewOptions().LockOnMake(false).SetLogger(nil))
+		g.Get(handler)
+		h := g.Make()
+
+		g.Post(handler)
+		h = g.Make()
+
+		rr := httptest.NewRecorder()
+		h(rr, httptest.NewRequest(http.MethodPost, "/", nil))
+		if rr.Body.String() != "test" {
+			t.Logf("unexpected response: %s", rr.Body.String())
+			t.Fail()
+		}
+	})
+}
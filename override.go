@@ -0,0 +1,67 @@
+package rgroup
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Header used by clients to override the method of a POST request.
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// Form field used by clients to override the method of a POST request.
+const MethodOverrideField = "_method"
+
+// Allow POST requests to the HandlerGroup to override their method with one of methods,
+// using the X-HTTP-Method-Override header or the _method form field.
+// This overrides any HandlerMux the group is added to.
+func (h *HandlerGroup) SetMethodOverride(methods ...string) *HandlerGroup {
+	h.options.SetMethodOverride(methods...)
+
+	return h
+}
+
+// Allow POST requests to all handler groups in mux to override their method with one of methods.
+// Values set on a HandlerGroup or a nested HandlerMux take precedence.
+// Plain http.Handlers added to the mux are not affected.
+func (m *HandlerMux) SetMethodOverride(methods ...string) *HandlerMux {
+	m.options.SetMethodOverride(methods...)
+	return m
+}
+
+// overrideMethod returns req with the method requested by the client if it is in allowed.
+// A 400 HandlerError is returned if the requested method is not allowed.
+func overrideMethod(req *http.Request, allowed []string) (*http.Request, error) {
+	if len(allowed) == 0 || req.Method != http.MethodPost {
+		return req, nil
+	}
+
+	method := req.Header.Get(MethodOverrideHeader)
+	if method == "" && isFormContent(req) {
+		method = req.PostFormValue(MethodOverrideField)
+	}
+
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if method == "" || method == req.Method {
+		return req, nil
+	}
+
+	for _, m := range allowed {
+		if m == method {
+			r := new(http.Request)
+			*r = *req
+			r.Method = method
+
+			return r, nil
+		}
+	}
+
+	return req, Error(http.StatusBadRequest).
+		WithResponse("method override %s not allowed", method).
+		WithMessage("method override %s not allowed", method)
+}
+
+func isFormContent(req *http.Request) bool {
+	ct := req.Header.Get("Content-Type")
+
+	return strings.HasPrefix(ct, "application/x-www-form-urlencoded") || strings.HasPrefix(ct, "multipart/form-data")
+}
@@ -0,0 +1,88 @@
+package rgroup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestMethodOverride(t *testing.T) {
+	Config.Reset()
+	defer Config.Reset()
+
+	var logged *LoggerData
+	Config.SetGlobalLogger(func(l *LoggerData) { logged = l })
+
+	handler := func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response(req.Method), nil
+	}
+
+	g := NewWithHandlers(HandlerMap{
+		http.MethodPost:   handler,
+		http.MethodDelete: handler,
+		http.MethodPut:    handler,
+	})
+
+	mux := NewServeMux().SetMethodOverride("delete", "put")
+	mux.Handle("/items", g)
+
+	form := func(method string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(url.Values{MethodOverrideField: {method}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req
+	}
+
+	header := func(reqMethod string, method string) *http.Request {
+		req := httptest.NewRequest(reqMethod, "/items", nil)
+		req.Header.Set(MethodOverrideHeader, method)
+		return req
+	}
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+		method string
+	}{
+		{name: "header", req: header(http.MethodPost, "DELETE"), status: http.StatusOK, method: http.MethodDelete},
+		{name: "form", req: form("put"), status: http.StatusOK, method: http.MethodPut},
+		{name: "none", req: httptest.NewRequest(http.MethodPost, "/items", nil), status: http.StatusOK, method: http.MethodPost},
+		{name: "not allowed", req: header(http.MethodPost, "PATCH"), status: http.StatusBadRequest, method: http.MethodPost},
+		{name: "not post", req: header(http.MethodPut, "DELETE"), status: http.StatusOK, method: http.MethodPut},
+	}
+
+	for _, tt := range tests {
+		res := httptest.NewRecorder()
+		mux.ServeHTTP(res, tt.req)
+
+		switch {
+		case res.Code != tt.status:
+			t.Logf("%s: expected %d, got %d %s", tt.name, tt.status, res.Code, res.Body.String())
+			t.Fail()
+		case logged.Method != tt.method || logged.Request.Method != tt.req.Method:
+			t.Logf("%s: unexpected logged methods %s %s", tt.name, logged.Request.Method, logged.Method)
+			t.Fail()
+		case tt.status == http.StatusOK && res.Body.String() != tt.method:
+			t.Logf("%s: unexpected response %s", tt.name, res.Body.String())
+			t.Fail()
+		}
+	}
+
+	if s := logged.String(); !strings.HasPrefix(s, "PUT 200") {
+		t.Logf("unexpected log line %s", s)
+		t.Fail()
+	}
+
+	// disabled on the group
+	g.SetMethodOverride()
+
+	res := httptest.NewRecorder()
+	g.ServeHTTP(res, header(http.MethodPost, "DELETE"))
+
+	if res.Body.String() != http.MethodPost {
+		t.Logf("expected override to be disabled, got %s", res.Body.String())
+		t.Fail()
+	}
+}
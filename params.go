@@ -0,0 +1,395 @@
+package rgroup
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// pathPattern is a HandlerMux pattern with parameters, e.g. /users/{id}/posts/{path...}
+type pathPattern struct {
+	raw      string
+	segments []patternSegment
+	// pattern ends with a slash and matches all paths below it, as with http.ServeMux
+	prefix bool
+}
+
+type patternSegment struct {
+	literal  string
+	param    string
+	wildcard bool
+}
+
+// hasParams reports whether p contains path parameters.
+func hasParams(p string) bool {
+	return strings.Contains(p, "{")
+}
+
+func parsePattern(p string) (*pathPattern, error) {
+	if !strings.HasPrefix(p, "/") {
+		return nil, fmt.Errorf("pattern %s must start with /", p)
+	}
+
+	pp := &pathPattern{raw: p}
+
+	trimmed := strings.TrimPrefix(p, "/")
+	if strings.HasSuffix(trimmed, "/") {
+		pp.prefix = true
+		trimmed = strings.TrimSuffix(trimmed, "/")
+	}
+
+	names := make(map[string]bool)
+	parts := strings.Split(trimmed, "/")
+
+	for i, part := range parts {
+		if !strings.ContainsAny(part, "{}") {
+			pp.segments = append(pp.segments, patternSegment{literal: part})
+			continue
+		}
+
+		if !strings.HasPrefix(part, "{") || !strings.HasSuffix(part, "}") {
+			return nil, fmt.Errorf("pattern %s: parameter must be a full path segment", p)
+		}
+
+		name := part[1 : len(part)-1]
+		seg := patternSegment{param: name}
+
+		if strings.HasSuffix(name, "...") {
+			if i != len(parts)-1 || pp.prefix {
+				return nil, fmt.Errorf("pattern %s: wildcard must be the last segment", p)
+			}
+			seg.param = strings.TrimSuffix(name, "...")
+			seg.wildcard = true
+		}
+
+		if !validParamName(seg.param) {
+			return nil, fmt.Errorf("pattern %s: invalid parameter name %q", p, seg.param)
+		}
+
+		if names[seg.param] {
+			return nil, fmt.Errorf("pattern %s: duplicate parameter %s", p, seg.param)
+		}
+		names[seg.param] = true
+
+		pp.segments = append(pp.segments, seg)
+	}
+
+	return pp, nil
+}
+
+func validParamName(n string) bool {
+	if n == "" {
+		return false
+	}
+
+	for i, c := range n {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && c >= '0' && c <= '9':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// match matches path against the pattern and returns the path parameters.
+func (p *pathPattern) match(path string) (map[string]string, bool) {
+	return p.matchFold(path, false)
+}
+
+// matchFold matches path against the pattern, comparing literal segments case-insensitively if fold is set.
+func (p *pathPattern) matchFold(path string, fold bool) (map[string]string, bool) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, false
+	}
+
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	params := make(map[string]string)
+
+	for i, seg := range p.segments {
+		if seg.wildcard {
+			params[seg.param] = strings.Join(parts[i:], "/")
+			return params, true
+		}
+
+		if i >= len(parts) {
+			return nil, false
+		}
+
+		switch {
+		case seg.param != "":
+			if parts[i] == "" {
+				return nil, false
+			}
+			params[seg.param] = parts[i]
+		case fold && !strings.EqualFold(seg.literal, parts[i]):
+			return nil, false
+		case !fold && seg.literal != parts[i]:
+			return nil, false
+		}
+	}
+
+	rest := parts[len(p.segments):]
+	switch {
+	case p.prefix:
+		// the trailing slash must be present
+		if len(rest) == 0 {
+			return nil, false
+		}
+	case len(rest) > 0:
+		return nil, false
+	}
+
+	return params, true
+}
+
+// canonical returns path with the literal segments matched by the pattern in their registered case.
+func (p *pathPattern) canonical(path string) string {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+
+	for i, seg := range p.segments {
+		if seg.wildcard || i >= len(parts) {
+			break
+		}
+
+		if seg.param == "" {
+			parts[i] = seg.literal
+		}
+	}
+
+	return "/" + strings.Join(parts, "/")
+}
+
+// shadowedBy reports whether the static http.ServeMux pattern, matching the same path as p,
+// takes precedence over p. Exact static patterns always win; as with the Go 1.22 http.ServeMux,
+// prefix patterns only win if they are more specific, e.g. /users/admin/ over /users/{id}/,
+// but not /users/ over /users/{id}.
+func (p *pathPattern) shadowedBy(static string) bool {
+	switch {
+	case !strings.HasSuffix(static, "/"):
+		return true
+	case static == "/":
+		return false
+	}
+
+	sp, err := parsePattern(static)
+	if err != nil {
+		return true
+	}
+
+	return sp.moreSpecific(p)
+}
+
+// moreSpecific reports whether p should be matched before o.
+func (p *pathPattern) moreSpecific(o *pathPattern) bool {
+	for i := 0; i < len(p.segments) && i < len(o.segments); i++ {
+		if r1, r2 := p.segments[i].rank(), o.segments[i].rank(); r1 != r2 {
+			return r1 < r2
+		}
+	}
+
+	if len(p.segments) != len(o.segments) {
+		return len(p.segments) > len(o.segments)
+	}
+
+	if p.prefix != o.prefix {
+		return !p.prefix
+	}
+
+	return p.raw < o.raw
+}
+
+func (s patternSegment) rank() int {
+	switch {
+	case s.wildcard:
+		return 2
+	case s.param != "":
+		return 1
+	default:
+		return 0
+	}
+}
+
+type routeContextKey struct{}
+
+// routeContext holds the routing information of a request.
+type routeContext struct {
+	// prefix stripped by the enclosing muxes
+	base    string
+	pattern string
+	params  map[string]string
+	// parameters captured from the host
+	host map[string]string
+	// request log and config of the HandlerGroup serving the request, used by FromHTTPMiddleware
+	log    *LoggerData
+	config *globalConfig
+}
+
+func routeFrom(ctx context.Context) *routeContext {
+	rc, _ := ctx.Value(routeContextKey{}).(*routeContext)
+	return rc
+}
+
+// withRoute returns req with the matched pattern and params added to the routing context.
+// Params of enclosing muxes are retained.
+func withRoute(req *http.Request, base string, pattern string, params map[string]string) *http.Request {
+	rc := &routeContext{base: base, pattern: base + pattern, params: params}
+
+	if parent := routeFrom(req.Context()); parent != nil {
+		rc.params = mergeParams(parent.params, params)
+		rc.host = parent.host
+	}
+
+	req = req.WithContext(context.WithValue(req.Context(), routeContextKey{}, rc))
+	setPathValues(req, params)
+
+	return req
+}
+
+// mergeParams returns the union of parent and params, params taking precedence.
+// parent is returned unchanged if params is empty.
+func mergeParams(parent map[string]string, params map[string]string) map[string]string {
+	if len(parent) == 0 {
+		return params
+	}
+
+	if len(params) == 0 {
+		return parent
+	}
+
+	merged := make(map[string]string, len(parent)+len(params))
+	for k, v := range parent {
+		merged[k] = v
+	}
+	for k, v := range params {
+		merged[k] = v
+	}
+
+	return merged
+}
+
+// withGroup returns req with the LoggerData and config of the HandlerGroup or Handler serving it.
+// The routing context of the innermost mux is updated in place; requests served without
+// a HandlerMux get a new routing context.
+func withGroup(req *http.Request, l *LoggerData, c *globalConfig) *http.Request {
+	if rc := routeFrom(req.Context()); rc != nil {
+		rc.log, rc.config = l, c
+		return req
+	}
+
+	rc := &routeContext{log: l, config: c}
+
+	return req.WithContext(context.WithValue(req.Context(), routeContextKey{}, rc))
+}
+
+// groupLog returns the LoggerData of the HandlerGroup serving req.
+func groupLog(req *http.Request) *LoggerData {
+	if rc := routeFrom(req.Context()); rc != nil && rc.log != nil {
+		return rc.log
+	}
+
+	return fromRequest(*req)
+}
+
+// routeBase returns the prefix stripped by the enclosing muxes of the request.
+func routeBase(req *http.Request) string {
+	if rc := routeFrom(req.Context()); rc != nil {
+		return rc.base
+	}
+
+	return ""
+}
+
+// PathParam returns the value of the named path parameter, or an empty string if it does not exist.
+// When built with Go 1.22 or later, values set by http.ServeMux (see http.Request.PathValue) are also returned.
+func PathParam(req *http.Request, name string) string {
+	if rc := routeFrom(req.Context()); rc != nil {
+		if v, ok := rc.params[name]; ok {
+			return v
+		}
+	}
+
+	return pathValue(req, name)
+}
+
+// PathParams returns a copy of all path parameters of the request.
+func PathParams(req *http.Request) map[string]string {
+	params := make(map[string]string)
+
+	if rc := routeFrom(req.Context()); rc != nil {
+		for k, v := range rc.params {
+			params[k] = v
+		}
+	}
+
+	return params
+}
+
+// PathInt returns the named path parameter as an int.
+// A 400 HandlerError is returned if the parameter is missing or not an integer.
+func PathInt(req *http.Request, name string) (int, error) {
+	v := PathParam(req, name)
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, Error(http.StatusBadRequest).
+			WithResponse("invalid path parameter %s", name).
+			WithMessage("invalid path parameter %s=%q", name, v).
+			Wrap(err)
+	}
+
+	return i, nil
+}
+
+// PathUUID returns the named path parameter, validated as a UUID in its canonical textual form.
+// A 400 HandlerError is returned if the parameter is missing or not a UUID.
+func PathUUID(req *http.Request, name string) (string, error) {
+	v := PathParam(req, name)
+
+	if !validUUID(v) {
+		return "", Error(http.StatusBadRequest).
+			WithResponse("invalid path parameter %s", name).
+			WithMessage("invalid path parameter %s=%q", name, v)
+	}
+
+	return v, nil
+}
+
+// PathString returns the named path parameter.
+// A 400 HandlerError is returned if the parameter is missing or empty.
+func PathString(req *http.Request, name string) (string, error) {
+	v := PathParam(req, name)
+
+	if v == "" {
+		return "", Error(http.StatusBadRequest).
+			WithResponse("missing path parameter %s", name).
+			WithMessage("missing path parameter %s", name)
+	}
+
+	return v, nil
+}
+
+func validUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+
+	for i, c := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
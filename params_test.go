@@ -0,0 +1,158 @@
+package rgroup
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	for _, p := range []string{"users/{id}", "/users/{id", "/users/x{id}", "/users/{id}/{id}", "/files/{path...}/x", "/files/{1id}"} {
+		if _, err := parsePattern(p); err == nil {
+			t.Logf("expected error for %s", p)
+			t.Fail()
+		}
+	}
+
+	type testMatch struct {
+		pattern string
+		path    string
+		params  map[string]string
+	}
+
+	for _, m := range []testMatch{
+		{pattern: "/users/{id}", path: "/users/1", params: map[string]string{"id": "1"}},
+		{pattern: "/users/{id}", path: "/users/1/", params: nil},
+		{pattern: "/users/{id}", path: "/users/", params: nil},
+		{pattern: "/users/{id}/", path: "/users/1/posts", params: map[string]string{"id": "1"}},
+		{pattern: "/users/{id}/", path: "/users/1", params: nil},
+		{pattern: "/files/{path...}", path: "/files/a/b/c", params: map[string]string{"path": "a/b/c"}},
+		{pattern: "/files/{path...}", path: "/files", params: map[string]string{"path": ""}},
+	} {
+		pp, err := parsePattern(m.pattern)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		params, ok := pp.match(m.path)
+		if ok != (m.params != nil) || fmt.Sprint(params) != fmt.Sprint(m.params) {
+			t.Logf("unexpected match %s %s: %v %v", m.pattern, m.path, ok, params)
+			t.Fail()
+		}
+	}
+}
+
+func TestPathParams(t *testing.T) {
+	var pattern string
+
+	g := New().SetOptions(NewOptions().SetLogger(func(ld *LoggerData) { pattern = ld.Pattern }))
+	g.Get(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		id, err := PathInt(req, "id")
+		if err != nil {
+			return nil, err
+		}
+
+		post, err := PathUUID(req, "postID")
+		if err != nil {
+			return nil, err
+		}
+
+		return Response(fmt.Sprintf("%d %s", id, post)), nil
+	})
+
+	files := New().SetOptions(NewOptions().SetLogger(func(ld *LoggerData) { pattern = ld.Pattern }))
+	files.Get(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response(PathParam(req, "tenant") + ":" + PathParam(req, "path")), nil
+	})
+
+	static := New().SetOptions(NewOptions().SetLogger(func(ld *LoggerData) { pattern = ld.Pattern }))
+	static.Get(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response("static"), nil
+	})
+
+	sub := NewServeMux().SetPrefix("/tenants")
+	sub.Handle("/{tenant}/files/{path...}", files)
+
+	mux := NewServeMux()
+	mux.Handle("/users/{id}/posts/{postID}", g)
+	mux.Handle("/users/me/posts/", static)
+	mux.Handle("/tenants/", sub)
+
+	type testRoute struct {
+		path     string
+		status   int
+		response string
+		pattern  string
+	}
+
+	for _, r := range []testRoute{
+		{path: "/users/12/posts/6ba7b810-9dad-11d1-80b4-00c04fd430c8", status: http.StatusOK, response: "12 6ba7b810-9dad-11d1-80b4-00c04fd430c8", pattern: "/users/{id}/posts/{postID}"},
+		{path: "/users/abc/posts/6ba7b810-9dad-11d1-80b4-00c04fd430c8", status: http.StatusBadRequest, response: "invalid path parameter id", pattern: "/users/{id}/posts/{postID}"},
+		{path: "/users/12/posts/1", status: http.StatusBadRequest, response: "invalid path parameter postID", pattern: "/users/{id}/posts/{postID}"},
+		{path: "/users/me/posts/1", status: http.StatusOK, response: "static", pattern: "/users/me/posts/"},
+		{path: "/tenants/t1/files/a/b.txt", status: http.StatusOK, response: "t1:a/b.txt", pattern: "/tenants/{tenant}/files/{path...}"},
+	} {
+		pattern = ""
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, r.path, nil))
+
+		if rr.Code != r.status || rr.Body.String() != r.response || pattern != r.pattern {
+			t.Logf("unexpected response for %s: %d %s (%s)", r.path, rr.Code, rr.Body.String(), pattern)
+			t.Fail()
+		}
+	}
+}
+
+func TestPathAccessors(t *testing.T) {
+	req := withRoute(httptest.NewRequest(http.MethodGet, "/", nil), "", "/", map[string]string{"id": "1", "name": ""})
+
+	if _, err := PathString(req, "name"); err == nil {
+		t.Log("expected error for empty parameter")
+		t.Fail()
+	}
+
+	herr := new(HandlerError)
+	if _, err := PathInt(req, "missing"); !errors.As(err, &herr) || herr.HTTPStatus != http.StatusBadRequest {
+		t.Logf("unexpected error: %v", err)
+		t.Fail()
+	}
+
+	if p := PathParams(req); len(p) != 2 || p["id"] != "1" {
+		t.Logf("unexpected params: %v", p)
+		t.Fail()
+	}
+}
+
+func TestPrefixPrecedence(t *testing.T) {
+	respond := func(s string) Handler {
+		return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return Response(s + PathParam(req, "id")), nil
+		}
+	}
+
+	mux := NewServeMux()
+	mux.Handle("/users/", respond("list"))
+	mux.Handle("/users/{id}", respond("user "))
+	mux.Handle("/users/admin/", respond("admin"))
+	mux.Handle("/groups/{id}/", respond("group "))
+
+	for path, response := range map[string]string{
+		"/users/":          "list",
+		"/users/5":         "user 5",
+		"/users/5/posts":   "list",
+		"/users/admin/":    "admin",
+		"/users/admin/x":   "admin",
+		"/groups/7/":       "group 7",
+		"/groups/7/member": "group 7",
+	} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rr.Code != http.StatusOK || rr.Body.String() != response {
+			t.Logf("unexpected response for %s: %d %s", path, rr.Code, rr.Body.String())
+			t.Fail()
+		}
+	}
+}
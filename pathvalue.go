@@ -0,0 +1,14 @@
+//go:build !go1.22
+
+package rgroup
+
+import (
+	"net/http"
+)
+
+// setPathValues is a no-op before Go 1.22.
+func setPathValues(req *http.Request, params map[string]string) {}
+
+func pathValue(req *http.Request, name string) string {
+	return ""
+}
@@ -0,0 +1,18 @@
+//go:build go1.22
+
+package rgroup
+
+import (
+	"net/http"
+)
+
+// setPathValues makes path parameters available through http.Request.PathValue.
+func setPathValues(req *http.Request, params map[string]string) {
+	for k, v := range params {
+		req.SetPathValue(k, v)
+	}
+}
+
+func pathValue(req *http.Request, name string) string {
+	return req.PathValue(name)
+}
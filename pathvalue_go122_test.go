@@ -0,0 +1,31 @@
+//go:build go1.22
+
+package rgroup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPathValue(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response(req.PathValue("id")), nil
+	})
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/items/12", nil))
+
+	if rr.Body.String() != "12" {
+		t.Logf("unexpected response: %s", rr.Body.String())
+		t.Fail()
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetPathValue("id", "34")
+	if v := PathParam(req, "id"); v != "34" {
+		t.Logf("unexpected path value: %s", v)
+		t.Fail()
+	}
+}
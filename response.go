@@ -12,6 +12,7 @@ func Response(data any) *HandlerResponse {
 		HTTPStatus: http.StatusOK,
 		LogMessage: "",
 		Headers:    map[string]string{},
+		Meta:       nil,
 	}
 
 	return &res
@@ -22,6 +23,11 @@ type HandlerResponse struct {
 	HTTPStatus int
 	LogMessage string
 	Headers    map[string]string
+	Meta       map[string]any
+	raw        bool
+	// response already written to the client by a standard middleware
+	written bool
+	size    int
 }
 
 // Set HTTP status code
@@ -44,6 +50,34 @@ func (r *HandlerResponse) WithHeader(header string, value string) *HandlerRespon
 	return r
 }
 
+// Add a meta entry to be included in enveloped responses.
+func (r *HandlerResponse) WithMeta(key string, value any) *HandlerResponse {
+	if r.Meta == nil {
+		r.Meta = make(map[string]any)
+	}
+
+	r.Meta[key] = value
+
+	return r
+}
+
+// Send the response as-is, bypassing envelope responses.
+func (r *HandlerResponse) Raw() *HandlerResponse {
+	r.raw = true
+
+	return r
+}
+
+// IsRaw reports whether the response bypasses envelope responses.
+// Responses with []byte data are always sent as-is.
+func (r *HandlerResponse) IsRaw() bool {
+	if _, ok := r.Data.([]byte); ok {
+		return true
+	}
+
+	return r.raw
+}
+
 func (r *HandlerResponse) DeleteHeader(header string) *HandlerResponse {
 	delete(r.Headers, header)
 
@@ -52,20 +86,7 @@ func (r *HandlerResponse) DeleteHeader(header string) *HandlerResponse {
 
 // Create Envelope from response.
 func (r *HandlerResponse) ToEnvelope() *Envelope {
-	e := Envelope{
-		Data: r.Data,
-		Status: EnvelopeStatus{
-			HTTPStatus: r.HTTPStatus,
-			Message:    nil,
-			Error:      nil,
-		},
-	}
-
-	if Config.Envelope.forwardLogMessage && r.LogMessage != "" {
-		e.Status.Message = &r.LogMessage
-	}
-
-	return &e
+	return newResponseEnvelope(r, loadConfig().Envelope.forwardLogMessage)
 }
 
 // Status struct for Envelope
@@ -79,4 +100,5 @@ type EnvelopeStatus struct {
 type Envelope struct {
 	Data   any            `json:"data,omitempty"`
 	Status EnvelopeStatus `json:"status"`
+	Meta   map[string]any `json:"meta,omitempty"`
 }
@@ -26,25 +26,26 @@ func defaultLogger(r *LoggerData) {
 	}
 }
 
-func writeErr(w http.ResponseWriter, err *HandlerError) int {
+func writeErr(w http.ResponseWriter, err *HandlerError, c *globalConfig) int {
 	if err == nil {
 		return 0
 	}
 
-	if Config.Envelope.enabled {
-		env := err.ToEnvelope()
+	if c.Envelope.enabled && !err.raw {
+		env := c.Envelope.builder.FromError(err, c.Envelope.forwardLogMessage)
 
-		if Config.Envelope.forwardHTTPStatus {
-			w.WriteHeader(err.HTTPStatus)
+		status := 0
+		if c.Envelope.forwardHTTPStatus {
+			status = err.HTTPStatus
 		}
 
-		return write(w, env)
+		return writeEnvelope(w, c.Envelope.builder, env, status)
 	}
 
 	w.WriteHeader(err.HTTPStatus)
 
 	res := err.Response
-	if errLog := err.Error(); Config.forwardErrorLog && errLog != "" {
+	if errLog := err.Error(); c.forwardErrorLog && errLog != "" {
 		res = fmt.Sprintf("%s: %s", res, errLog)
 	}
 
@@ -55,25 +56,30 @@ func writeErr(w http.ResponseWriter, err *HandlerError) int {
 	return 0
 }
 
-func writeRes(w http.ResponseWriter, res *HandlerResponse) int {
+func writeRes(w http.ResponseWriter, res *HandlerResponse, c *globalConfig) int {
 	if res == nil {
 		return 0
 	}
 
+	if res.written {
+		return res.size
+	}
+
 	if len(res.Headers) > 0 {
 		for h, v := range res.Headers {
 			w.Header().Add(h, v)
 		}
 	}
 
-	if _, ok := res.Data.([]byte); !ok && Config.Envelope.enabled {
-		env := res.ToEnvelope()
+	if c.Envelope.enabled && !res.IsRaw() {
+		env := c.Envelope.builder.FromResponse(res, c.Envelope.forwardLogMessage)
 
-		if Config.Envelope.forwardHTTPStatus && (res.HTTPStatus != http.StatusOK) {
-			w.WriteHeader(res.HTTPStatus)
+		status := 0
+		if c.Envelope.forwardHTTPStatus && (res.HTTPStatus != http.StatusOK) {
+			status = res.HTTPStatus
 		}
 
-		return write(w, env)
+		return writeEnvelope(w, c.Envelope.builder, env, status)
 	}
 
 	if res.HTTPStatus != http.StatusOK {
@@ -112,15 +118,14 @@ func write(w http.ResponseWriter, d any) int {
 	return n
 }
 
-func logAndWrite(w http.ResponseWriter, l *LoggerData, logger func(*LoggerData)) {
+func logAndWrite(w http.ResponseWriter, l *LoggerData, logger func(*LoggerData), c *globalConfig) {
+	defer logRequest(l, logger, c)
 
-	defer func() {
-		if l.Request.Method != http.MethodOptions || Config.logOptions {
-			l.Duration()
-			logger(l)
-		}
-	}()
+	writeResult(w, l, c)
+}
 
+// writeResult writes the response or error of l to the client.
+func writeResult(w http.ResponseWriter, l *LoggerData, c *globalConfig) {
 	if l.err != nil {
 		me := new(HandlerError)
 		if !errors.As(l.err, &me) {
@@ -128,7 +133,18 @@ func logAndWrite(w http.ResponseWriter, l *LoggerData, logger func(*LoggerData))
 			_ = me.Wrap(l.err)
 		}
 
-		n := writeErr(w, me)
+		if me.written {
+			l.Error = me
+			l.ResponseSize = me.size
+
+			return
+		}
+
+		if c.Envelope.enabled && !me.raw {
+			me.Meta = addMeta(me.Meta, collectMeta(l, c.Envelope))
+		}
+
+		n := writeErr(w, me, c)
 
 		l.Error = me
 		l.ResponseSize = n
@@ -136,15 +152,37 @@ func logAndWrite(w http.ResponseWriter, l *LoggerData, logger func(*LoggerData))
 		return
 	}
 
-	if Config.prewriter != nil {
-		l.Response = Config.prewriter(&l.Request, l.Response)
+	if l.Response != nil && l.Response.written {
+		l.ResponseSize = l.Response.size
+
+		return
+	}
+
+	if c.prewriter != nil {
+		l.Response = c.prewriter(&l.Request, l.Response)
+	}
+
+	if c.Envelope.enabled && l.Response != nil && !l.Response.IsRaw() {
+		l.Response.Meta = addMeta(l.Response.Meta, collectMeta(l, c.Envelope))
 	}
 
-	n := writeRes(w, l.Response)
+	n := writeRes(w, l.Response, c)
 
 	l.ResponseSize = n
 }
 
+// logRequest calls the logger, skipping OPTIONS requests if they are not logged.
+func logRequest(l *LoggerData, logger func(*LoggerData), c *globalConfig) {
+	if logger == nil {
+		logger = c.logger
+	}
+
+	if l.Request.Method != http.MethodOptions || c.logOptions {
+		l.Duration()
+		logger(l)
+	}
+}
+
 type rwriter struct {
 	data    []byte
 	status  int
@@ -94,7 +94,7 @@ func TestDefaultLogger(t *testing.T) {
 
 func TestWriteErr(t *testing.T) {
 	rr := httptest.NewRecorder()
-	n := writeErr(rr, nil)
+	n := writeErr(rr, nil, &Config)
 	if n != 0 {
 		t.Logf("unexpected message length: %d", n)
 		t.Fail()
@@ -107,7 +107,7 @@ func TestWriteErr(t *testing.T) {
 	rr = httptest.NewRecorder()
 	err := Error(http.StatusNotAcceptable).WithMessage("test error").WithResponse("test response")
 
-	writeErr(rr, err)
+	writeErr(rr, err, &Config)
 	if rr.Body.String() != "test response" {
 		t.Logf("unexpected error response: %s", rr.Body.String())
 		t.Fail()
@@ -120,7 +120,7 @@ func TestWriteErr(t *testing.T) {
 	Config.SetForwardErrorLog(true)
 	rr = httptest.NewRecorder()
 
-	writeErr(rr, err)
+	writeErr(rr, err, &Config)
 	if rr.Body.String() != "test response: test error" {
 		t.Logf("unexpected error response: %s", rr.Body.String())
 		t.Fail()
@@ -136,7 +136,7 @@ func TestWriteErr(t *testing.T) {
 		Config.Envelope.Enable()
 		rr = httptest.NewRecorder()
 
-		writeErr(rr, err)
+		writeErr(rr, err, &Config)
 		if rr.Body.String() != "{\"status\":{\"http_status\":406,\"error\":\"test response\"}}" {
 			t.Logf("unexpected error message: %s", rr.Body.String())
 			t.Fail()
@@ -145,7 +145,7 @@ func TestWriteErr(t *testing.T) {
 		Config.Envelope.SetForwardLogMessage(true)
 		rr = httptest.NewRecorder()
 
-		writeErr(rr, err)
+		writeErr(rr, err, &Config)
 		if rr.Body.String() != "{\"status\":{\"http_status\":406,\"message\":\"test error\",\"error\":\"test response\"}}" {
 			t.Logf("unexpected error message: %s", rr.Body.String())
 			t.Fail()
@@ -154,7 +154,7 @@ func TestWriteErr(t *testing.T) {
 		Config.Envelope.SetForwardHTTPStatus(true)
 		rr = httptest.NewRecorder()
 
-		writeErr(rr, err)
+		writeErr(rr, err, &Config)
 		if rr.Body.String() != "{\"status\":{\"http_status\":406,\"message\":\"test error\",\"error\":\"test response\"}}" {
 			t.Logf("unexpected error message: %s", rr.Body.String())
 			t.Fail()
@@ -177,7 +177,7 @@ func TestWriteRes(t *testing.T) {
 		WithHeader("X-Test-1", "test1").
 		WithHeader("X-Test-2", "test2")
 
-	writeRes(rr, res)
+	writeRes(rr, res, &Config)
 
 	if rr.Code != http.StatusAccepted {
 		t.Logf("unexpected status: %d (%s)", rr.Code, http.StatusText(rr.Code))
@@ -196,7 +196,7 @@ func TestWriteRes(t *testing.T) {
 
 	Config.Envelope.Enable()
 	rr = httptest.NewRecorder()
-	writeRes(rr, res)
+	writeRes(rr, res, &Config)
 	if rr.Body.String() != "{\"data\":\"test data\",\"status\":{\"http_status\":202}}" {
 		t.Logf("unexpected response: %s", rr.Body.String())
 		t.Fail()
@@ -205,7 +205,7 @@ func TestWriteRes(t *testing.T) {
 	Config.Envelope.SetForwardHTTPStatus(true)
 	rr = httptest.NewRecorder()
 
-	writeRes(rr, res)
+	writeRes(rr, res, &Config)
 	if rr.Code != http.StatusAccepted {
 		t.Logf("unexpected status code: %d (%s)", rr.Code, http.StatusText(rr.Code))
 		t.Fail()
@@ -0,0 +1,249 @@
+package rgroup
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteInfo describes a route registered on a HandlerMux.
+type RouteInfo struct {
+	// Name of the route set with HandlerMux.HandleNamed.
+	Name string
+	// Host pattern of the route set with HandlerMux.HandleHost, empty for all hosts.
+	Host string
+	// Full path of the route, including the prefixes of the enclosing muxes.
+	Path string
+	// Methods handled by the route, sorted. Empty for plain http.Handlers, which handle all methods.
+	Methods []string
+	// Versions with versioned handlers, sorted.
+	Versions []string
+	// Names of the middleware applied to the route, in the order they run.
+	Middleware []string
+	// Names of the pre-middleware of the route and the enclosing muxes, in the order they run.
+	PreMiddleware []string
+	// Hooks of the route and the enclosing muxes as "event:name", in the order they run.
+	Hooks []string
+	// Name of the logger function of the route.
+	Logger string
+	// Metadata attached to the route and the enclosing muxes.
+	Metadata map[string]any
+}
+
+// Routes walks the HandlerMux and all nested muxes and returns the registered routes sorted by path and host.
+func (m *HandlerMux) Routes() []RouteInfo {
+	routes := m.routeInfo(m.prefix, buildContext{}.child(m), nil)
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Host < routes[j].Host
+	})
+
+	return routes
+}
+
+func (m *HandlerMux) routeInfo(base string, ctx buildContext, meta map[string]any) []RouteInfo {
+	defer m.rlock()()
+
+	meta = mergeMetadata(meta, m.metadata)
+	routes := make([]RouteInfo, 0, len(m.h))
+
+	names := make(map[string]string, len(m.names))
+	for n, p := range m.names {
+		if cur, ok := names[p]; !ok || n < cur {
+			names[p] = n
+		}
+	}
+
+	for _, p := range m.paths() {
+		routes = append(routes, routeEntries(base+p, names[p], m.h[p], base, ctx, meta)...)
+	}
+
+	for _, r := range m.hosts {
+		for _, e := range routeEntries(base+"/", "", r.h, base, ctx, meta) {
+			if e.Host == "" {
+				e.Host = r.pattern.raw
+			}
+			routes = append(routes, e)
+		}
+	}
+
+	return routes
+}
+
+// routeEntries returns the routes of h registered at path.
+func routeEntries(path string, name string, h http.Handler, base string, ctx buildContext, meta map[string]any) []RouteInfo {
+	r := RouteInfo{
+		Name:     name,
+		Path:     path,
+		Metadata: meta,
+	}
+
+	switch h := h.(type) {
+	case *HandlerMux:
+		return h.routeInfo(base+h.prefix, ctx.child(h), meta)
+	case *HandlerGroup:
+		r.Methods = h.methods()
+		if len(h.versions) > 0 {
+			r.Versions = h.versionNames()
+		}
+		r.Middleware = reverseNames(append(
+			taggedNames(h.middleware, h.tags, h.skip[""]),
+			taggedNames(ctx.middleware, ctx.tags, h.skip[""])...,
+		))
+		r.PreMiddleware = middlewareNames(h.pre, ctx.pre)
+		r.Hooks = hookNames(h.enabledHooks(ctx))
+		r.Metadata = mergeMetadata(meta, h.metadata)
+
+		logger := h.logger
+		if logger == nil {
+			logger = h.options.inherit(ctx.options).apply(lockedConfig()).logger
+		}
+		r.Logger = funcName(logger)
+	default:
+		r.Middleware = reverseNames(taggedNames(ctx.middleware, ctx.tags, nil))
+		r.PreMiddleware = middlewareNames(ctx.pre)
+		r.Logger = funcName(ctx.options.apply(lockedConfig()).logger)
+	}
+
+	return []RouteInfo{r}
+}
+
+// methods returns the methods handled by the HandlerGroup, including versioned handlers, sorted.
+func (h *HandlerGroup) methods() []string {
+	set := make(map[string]bool, len(h.handlers))
+	for k := range h.handlers {
+		set[k] = true
+	}
+
+	for _, v := range h.versions {
+		for k := range v {
+			set[k] = true
+		}
+	}
+
+	methods := make([]string, 0, len(set))
+	for k := range set {
+		methods = append(methods, k)
+	}
+	sort.Strings(methods)
+
+	return methods
+}
+
+// middlewareNames returns the names of the middleware in the order they run.
+// Each slice is in the order the middleware is applied, innermost first.
+func middlewareNames(middleware ...[]Middleware) []string {
+	names := make([]string, 0)
+	for _, mid := range middleware {
+		names = append(names, taggedNames(mid, nil, nil)...)
+	}
+
+	return reverseNames(names)
+}
+
+// taggedNames returns the names of the middleware not skipped for all methods, innermost first.
+// Named middleware is reported by its name, other middleware by its function name.
+func taggedNames(middleware []Middleware, tags []string, skip map[string]bool) []string {
+	names := make([]string, 0, len(middleware))
+	for i, m := range middleware {
+		switch {
+		case i >= len(tags) || tags[i] == "":
+			names = append(names, funcName(m))
+		case !skip[tags[i]]:
+			names = append(names, tags[i])
+		}
+	}
+
+	return names
+}
+
+// reverseNames reverses names in place and returns it.
+func reverseNames(names []string) []string {
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+
+	return names
+}
+
+func funcName(f any) string {
+	v := reflect.ValueOf(f)
+	if !v.IsValid() || v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+
+	if fn := runtime.FuncForPC(v.Pointer()); fn != nil {
+		return fn.Name()
+	}
+
+	return ""
+}
+
+func mergeMetadata(parent map[string]any, meta map[string]any) map[string]any {
+	if len(meta) == 0 {
+		return parent
+	}
+
+	merged := make(map[string]any, len(parent)+len(meta))
+	for k, v := range parent {
+		merged[k] = v
+	}
+	for k, v := range meta {
+		merged[k] = v
+	}
+
+	return merged
+}
+
+// PrintRoutes writes the route table of the HandlerMux to w, sorted by path.
+func PrintRoutes(w io.Writer, m *HandlerMux) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "PATH\tMETHODS\tMIDDLEWARE\tLOGGER")
+	for _, r := range m.Routes() {
+		methods := "*"
+		if len(r.Methods) > 0 {
+			methods = strings.Join(r.Methods, ",")
+		}
+
+		middleware := "-"
+		if len(r.Middleware) > 0 {
+			middleware = strings.Join(r.Middleware, ",")
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Path, methods, middleware, r.Logger)
+	}
+
+	return tw.Flush()
+}
+
+// Attach metadata to the HandlerGroup, reported by HandlerMux.Routes.
+func (h *HandlerGroup) SetMetadata(key string, value any) *HandlerGroup {
+	if h.metadata == nil {
+		h.metadata = make(map[string]any)
+	}
+
+	h.metadata[key] = value
+
+	return h
+}
+
+// Attach metadata to all routes of the HandlerMux, reported by HandlerMux.Routes.
+// Metadata set on a HandlerGroup or a nested HandlerMux takes precedence.
+func (m *HandlerMux) SetMetadata(key string, value any) *HandlerMux {
+	if m.metadata == nil {
+		m.metadata = make(map[string]any)
+	}
+
+	m.metadata[key] = value
+
+	return m
+}
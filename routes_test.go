@@ -0,0 +1,147 @@
+package rgroup
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testRouteMiddleware(h Handler) Handler { return h }
+
+func testRouteLogger(*LoggerData) {}
+
+func TestRoutes(t *testing.T) {
+	handler := func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return nil, nil
+	}
+
+	g1 := New().SetMetadata("owner", "team-a").AddMiddleware(testRouteMiddleware)
+	g1.Get(handler)
+	g1.Post(handler)
+
+	g2 := New()
+	g2.Delete(handler)
+	g2.SetLogger(testRouteLogger)
+
+	sub := NewServeMux().SetPrefix("/sub").SetMetadata("version", 2)
+	sub.Handle("/g2", g2)
+	sub.Handle("/static/", http.NotFoundHandler())
+
+	mux := NewServeMux().SetMetadata("owner", "team-b").AddMiddleware(func(h Handler) Handler { return h })
+	mux.Handle("/sub/", sub)
+	mux.Handle("/g1/{id}", g1)
+
+	routes := mux.Routes()
+	if len(routes) != 3 {
+		t.Fatalf("unexpected routes: %+v", routes)
+	}
+
+	r := routes[0]
+	switch {
+	case r.Path != "/g1/{id}" || strings.Join(r.Methods, ",") != "GET,POST":
+		t.Logf("unexpected route: %+v", r)
+		t.Fail()
+	case len(r.Middleware) != 2 || !strings.HasSuffix(r.Middleware[1], "testRouteMiddleware"):
+		t.Logf("unexpected middleware: %v", r.Middleware)
+		t.Fail()
+	case r.Metadata["owner"] != "team-a" || !strings.HasSuffix(r.Logger, "defaultLogger"):
+		t.Logf("unexpected route: %+v", r)
+		t.Fail()
+	}
+
+	r = routes[1]
+	switch {
+	case r.Path != "/sub/g2" || strings.Join(r.Methods, ",") != "DELETE":
+		t.Logf("unexpected route: %+v", r)
+		t.Fail()
+	case r.Metadata["owner"] != "team-b" || r.Metadata["version"] != 2 || !strings.HasSuffix(r.Logger, "testRouteLogger"):
+		t.Logf("unexpected route: %+v", r)
+		t.Fail()
+	}
+
+	if r = routes[2]; r.Path != "/sub/static/" || len(r.Methods) != 0 {
+		t.Logf("unexpected route: %+v", r)
+		t.Fail()
+	}
+
+	var b bytes.Buffer
+	if err := PrintRoutes(&b, mux); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "PATH") || !strings.HasPrefix(lines[3], "/sub/static/  *") {
+		t.Logf("unexpected route table:\n%s", b.String())
+		t.Fail()
+	}
+}
+
+func TestURL(t *testing.T) {
+	handler := func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return nil, nil
+	}
+
+	sub := NewServeMux().SetPrefix("/users")
+	sub.HandleNamed("user-post", "/{id}/posts/{postID}", Handler(handler))
+	sub.HandleNamed("user-files", "GET /{id}/files/{path...}", Handler(handler))
+
+	mux := NewServeMux()
+	mux.Handle("/users/", sub)
+	mux.HandleNamed("health", "/health", Handler(handler))
+	mux.HandleNamed("health", "/healthz", Handler(handler))
+
+	if err := mux.Validate(); err == nil || !strings.Contains(err.Error(), "duplicate route name health") {
+		t.Logf("expected duplicate name error, got %v", err)
+		t.Fail()
+	}
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		url    string
+		err    string
+	}{
+		{name: "health", url: "/health"},
+		{name: "user-post", params: map[string]string{"id": "7", "postID": "a b"}, url: "/users/7/posts/a%20b"},
+		{name: "user-files", params: map[string]string{"id": "7", "path": "docs/a.txt"}, url: "/users/7/files/docs/a.txt"},
+		{name: "user-post", params: map[string]string{"id": "7"}, err: "missing parameter postID"},
+		{name: "unknown", err: "route unknown not found"},
+	}
+
+	for _, tt := range tests {
+		u, err := mux.URL(tt.name, tt.params)
+		switch {
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Logf("%s: expected error %q, got %v", tt.name, tt.err, err)
+			t.Fail()
+		case tt.err == "" && (err != nil || u != tt.url):
+			t.Logf("%s: expected %s, got %s (%v)", tt.name, tt.url, u, err)
+			t.Fail()
+		}
+	}
+
+	if r := mux.Routes(); r[0].Name != "health" {
+		t.Logf("unexpected route: %+v", r[0])
+		t.Fail()
+	}
+
+	dup := NewServeMux()
+	dup.Handle("/items", Handler(handler))
+	dup.HandleNamed("items", "/items", Handler(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response("replaced"), nil
+	}))
+
+	rr := httptest.NewRecorder()
+	dup.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/items", nil))
+
+	switch err := dup.Validate(); {
+	case err == nil || !strings.Contains(err.Error(), "duplicate handler for /items"):
+		t.Logf("expected duplicate handler error, got %v", err)
+		t.Fail()
+	case rr.Body.String() == "replaced":
+		t.Log("duplicate named route registered")
+		t.Fail()
+	}
+}
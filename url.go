@@ -0,0 +1,131 @@
+package rgroup
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Add a named HandlerGroup.
+// The name can be used with HandlerMux.URL to build paths for the route.
+func (m *HandlerMux) HandleNamed(name string, path string, h http.Handler) {
+	m.update(func() {
+		m.handleNamed(name, path, h)
+	})
+}
+
+func (m *HandlerMux) handleNamed(name string, path string, h http.Handler) {
+	if name == "" {
+		m.fail(fmt.Errorf("empty name for %s", path))
+		return
+	}
+
+	if m.names == nil {
+		m.names = make(map[string]string)
+	}
+
+	if _, ok := m.names[name]; ok {
+		m.fail(fmt.Errorf("duplicate route name %s", name))
+		return
+	}
+
+	// duplicates are rejected as a whole, since handle registers them after recording the error;
+	// on any other error the route is not registered
+	method, p := splitPattern(path)
+	if g, ok := m.methods[p]; ok && method != "" {
+		if _, ok := g.handlers[method]; ok {
+			m.fail(fmt.Errorf("duplicate %s handler for %s", method, p))
+			return
+		}
+	} else if _, ok := m.h[p]; ok && method == "" {
+		m.fail(fmt.Errorf("duplicate handler for %s", p))
+		return
+	}
+
+	n := len(m.errs)
+	m.handle(path, h)
+	if len(m.errs) > n {
+		return
+	}
+
+	m.names[name] = p
+}
+
+// URL builds the path of the named route, including the prefixes of the enclosing muxes.
+// Named routes of nested muxes are also resolved.
+// An error is returned if the route does not exist or a path parameter is missing.
+func (m *HandlerMux) URL(name string, params map[string]string) (string, error) {
+	pattern, ok := m.findNamed(name, m.prefix)
+	if !ok {
+		return "", fmt.Errorf("[rgroup] route %s not found", name)
+	}
+
+	if !hasParams(pattern) {
+		return pattern, nil
+	}
+
+	pp, err := parsePattern(pattern)
+	if err != nil {
+		return "", fmt.Errorf("[rgroup] route %s: %w", name, err)
+	}
+
+	var b strings.Builder
+	for _, seg := range pp.segments {
+		b.WriteString("/")
+
+		if seg.param == "" {
+			b.WriteString(seg.literal)
+			continue
+		}
+
+		v, ok := params[seg.param]
+		if !ok || (v == "" && !seg.wildcard) {
+			return "", fmt.Errorf("[rgroup] route %s: missing parameter %s", name, seg.param)
+		}
+
+		if !seg.wildcard {
+			b.WriteString(url.PathEscape(v))
+			continue
+		}
+
+		parts := strings.Split(v, "/")
+		for i, p := range parts {
+			parts[i] = url.PathEscape(p)
+		}
+		b.WriteString(strings.Join(parts, "/"))
+	}
+
+	if pp.prefix {
+		b.WriteString("/")
+	}
+
+	return b.String(), nil
+}
+
+// findNamed returns the full pattern of the named route.
+func (m *HandlerMux) findNamed(name string, base string) (string, bool) {
+	defer m.rlock()()
+
+	if p, ok := m.names[name]; ok {
+		return base + p, true
+	}
+
+	for _, p := range m.paths() {
+		if sub, ok := m.h[p].(*HandlerMux); ok {
+			if found, ok := sub.findNamed(name, base+sub.prefix); ok {
+				return found, true
+			}
+		}
+	}
+
+	for _, r := range m.hosts {
+		if sub, ok := r.h.(*HandlerMux); ok {
+			if found, ok := sub.findNamed(name, base+sub.prefix); ok {
+				return found, true
+			}
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,126 @@
+package rgroup
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ValidationError lists the problems found while building a HandlerGroup or HandlerMux.
+// These include registrations dropped after Make, duplicate and nil handlers and invalid method names.
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+
+	return "[rgroup] invalid configuration: " + strings.Join(msgs, "; ")
+}
+
+// Validate returns a *ValidationError listing all problems found while building the
+// HandlerGroup, or nil if there are none.
+func (h *HandlerGroup) Validate() error {
+	if len(h.errs) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Errors: append([]error(nil), h.errs...)}
+}
+
+// MustMake calls Validate and panics on error, otherwise it returns the result of Make.
+func (h *HandlerGroup) MustMake() http.HandlerFunc {
+	if err := h.Validate(); err != nil {
+		panic(err)
+	}
+
+	f := h.Make()
+
+	if err := h.Validate(); err != nil {
+		panic(err)
+	}
+
+	return f
+}
+
+// Validate returns a *ValidationError listing all problems found while building the
+// HandlerMux and all handler groups and muxes added to it, or nil if there are none.
+func (m *HandlerMux) Validate() error {
+	defer m.rlock()()
+
+	errs := append([]error(nil), m.errs...)
+
+	for _, p := range m.paths() {
+		var err error
+		switch h := m.h[p].(type) {
+		case *HandlerGroup:
+			err = h.Validate()
+		case *HandlerMux:
+			err = h.Validate()
+		}
+
+		if verr, ok := err.(*ValidationError); ok {
+			for _, e := range verr.Errors {
+				errs = append(errs, fmt.Errorf("%s: %w", p, e))
+			}
+		}
+	}
+
+	for _, r := range m.hosts {
+		var err error
+		switch h := r.h.(type) {
+		case *HandlerGroup:
+			err = h.Validate()
+		case *HandlerMux:
+			err = h.Validate()
+		}
+
+		if verr, ok := err.(*ValidationError); ok {
+			for _, e := range verr.Errors {
+				errs = append(errs, fmt.Errorf("host %s: %w", r.pattern.raw, e))
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Errors: errs}
+}
+
+// MustMake calls Validate and panics on error, otherwise it returns the result of Make.
+func (m *HandlerMux) MustMake() http.Handler {
+	if err := m.Validate(); err != nil {
+		panic(err)
+	}
+
+	h := m.Make()
+
+	if err := m.Validate(); err != nil {
+		panic(err)
+	}
+
+	return h
+}
+
+// validMethod reports whether m is a valid http method token.
+func validMethod(m string) bool {
+	if m == "" {
+		return false
+	}
+
+	for _, c := range m {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", c):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
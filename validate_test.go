@@ -0,0 +1,101 @@
+package rgroup
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	handler := func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response("test"), nil
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		g := New()
+		g.Get(handler)
+
+		if err := g.Validate(); err != nil {
+			t.Logf("unexpected error: %s", err)
+			t.Fail()
+		}
+
+		if g.MustMake() == nil {
+			t.Log("expected handler func")
+			t.Fail()
+		}
+	})
+
+	t.Run("group", func(t *testing.T) {
+		g := New()
+		g.Get(handler)
+		g.Get(handler)
+		g.AddHandler("GET /", handler)
+		g.Post(nil)
+		g.AddMiddleware(nil)
+		g.Make()
+		g.Put(handler)
+		g.AddMiddleware(func(h Handler) Handler { return h })
+
+		verr := new(ValidationError)
+		if err := g.Validate(); !errors.As(err, &verr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(verr.Errors) != 6 {
+			t.Logf("unexpected errors: %s", verr)
+			t.Fail()
+		}
+
+		defer func() {
+			if recover() == nil {
+				t.Log("expected panic")
+				t.Fail()
+			}
+		}()
+		g.MustMake()
+	})
+
+	t.Run("mux", func(t *testing.T) {
+		g := New()
+		g.Get(handler)
+		g.Make()
+
+		mux := NewServeMux()
+		mux.Handle("/g", g)
+		mux.Handle("/g", g)
+		mux.Handle("/nil", nil)
+		mux.AddMiddleware(func(h Handler) Handler { return h })
+
+		sub := NewServeMux()
+		sub.Handle("/sub", New().SetOptions(NewOptions()))
+		sub.Handle("/sub", New())
+		mux.Handle("/sub/", sub)
+
+		mux.Make()
+
+		err := mux.Validate()
+		verr := new(ValidationError)
+		if !errors.As(err, &verr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(verr.Errors) != 3 || !strings.Contains(err.Error(), "/sub/: duplicate handler for /sub") {
+			t.Logf("unexpected errors: %s", err)
+			t.Fail()
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		g := New().SetOptions(NewOptions().SetStrict(true))
+
+		defer func() {
+			if recover() == nil {
+				t.Log("expected panic")
+				t.Fail()
+			}
+		}()
+		g.AddHandler("INVALID METHOD", handler)
+	})
+}
@@ -0,0 +1,158 @@
+package rgroup
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// VersionSource returns the API version requested by a request, or an empty string if none is requested.
+type VersionSource func(req *http.Request) string
+
+// VersionFromHeader reads the version from the request header, e.g. X-API-Version.
+func VersionFromHeader(header string) VersionSource {
+	return func(req *http.Request) string {
+		return strings.TrimSpace(req.Header.Get(header))
+	}
+}
+
+// VersionFromQuery reads the version from the query parameter of the request.
+func VersionFromQuery(param string) VersionSource {
+	return func(req *http.Request) string {
+		return req.URL.Query().Get(param)
+	}
+}
+
+// VersionFromAccept reads the version from a media type parameter of the Accept header,
+// e.g. Accept: application/json; version=2
+func VersionFromAccept(param string) VersionSource {
+	return func(req *http.Request) string {
+		for _, a := range strings.Split(strings.Join(req.Header.Values("Accept"), ","), ",") {
+			_, params, err := mime.ParseMediaType(strings.TrimSpace(a))
+			if err != nil {
+				continue
+			}
+
+			if v := params[param]; v != "" {
+				return v
+			}
+		}
+
+		return ""
+	}
+}
+
+// VersionFromPath reads the version from a path parameter of the HandlerMux pattern,
+// e.g. VersionFromPath("version") with the pattern /{version}/users
+func VersionFromPath(param string) VersionSource {
+	return func(req *http.Request) string {
+		return PathParam(req, param)
+	}
+}
+
+// Versioning selects the versioned handlers of a HandlerGroup.
+// Set it on a HandlerGroup, a HandlerMux or Options.
+type Versioning struct {
+	sources        []VersionSource
+	defaultVersion string
+	deprecated     map[string]time.Time
+}
+
+// Create a new Versioning reading the version from the first source returning a non-empty value.
+func NewVersioning(sources ...VersionSource) *Versioning {
+	return &Versioning{sources: sources}
+}
+
+// Set the version used when the request does not specify one.
+func (v *Versioning) SetDefault(version string) *Versioning {
+	v.defaultVersion = version
+
+	return v
+}
+
+// Mark a version as deprecated.
+// Responses for the version carry a Deprecation header and, unless sunset is zero, a Sunset header.
+func (v *Versioning) Deprecate(version string, sunset time.Time) *Versioning {
+	if v.deprecated == nil {
+		v.deprecated = make(map[string]time.Time)
+	}
+
+	v.deprecated[version] = sunset
+
+	return v
+}
+
+// resolve returns the version requested by req, or the default version.
+// requested reports whether the version was read from the request.
+func (v *Versioning) resolve(req *http.Request) (version string, requested bool) {
+	if v == nil {
+		return "", false
+	}
+
+	for _, s := range v.sources {
+		if version := s(req); version != "" {
+			return version, true
+		}
+	}
+
+	return v.defaultVersion, false
+}
+
+// setHeaders adds the deprecation headers of version to w.
+func (v *Versioning) setHeaders(w http.ResponseWriter, version string) {
+	if v == nil {
+		return
+	}
+
+	sunset, ok := v.deprecated[version]
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Deprecation", "true")
+	if !sunset.IsZero() {
+		w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+	}
+}
+
+// Set the Versioning of the HandlerGroup.
+// This overrides any HandlerMux the group is added to.
+func (h *HandlerGroup) SetVersioning(v *Versioning) *HandlerGroup {
+	h.options.SetVersioning(v)
+
+	return h
+}
+
+// Set the Versioning for all handler groups in mux.
+// Values set on a HandlerGroup or a nested HandlerMux take precedence.
+func (m *HandlerMux) SetVersioning(v *Versioning) *HandlerMux {
+	m.options.SetVersioning(v)
+	return m
+}
+
+// Adds a new Handler for a version to the HandlerGroup.
+// Requests for the version are served by its handlers, falling back to the handlers added
+// with AddHandler for other methods. Requests explicitly asking for a version without handlers
+// are rejected with 400; a default version without handlers is served by the handlers added with AddHandler.
+func (h *HandlerGroup) AddVersionedHandler(version string, method string, handler Handler) {
+	if version == "" {
+		h.fail(fmt.Errorf("empty version for %s handler", strings.ToUpper(method)))
+		return
+	}
+
+	h.addHandler(version, method, handler)
+}
+
+// versionNames returns the versions of the HandlerGroup, sorted.
+func (h *HandlerGroup) versionNames() []string {
+	versions := make([]string, 0, len(h.versions))
+	for v := range h.versions {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+
+	return versions
+}
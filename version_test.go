@@ -0,0 +1,158 @@
+package rgroup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVersioning(t *testing.T) {
+	Config.Reset()
+	defer Config.Reset()
+
+	var version string
+	Config.SetGlobalLogger(func(l *LoggerData) { version = l.Version })
+
+	handler := func(name string) Handler {
+		return func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return Response(name), nil
+		}
+	}
+
+	sunset := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	g := New()
+	g.Get(handler("get"))
+	g.AddVersionedHandler("1", http.MethodGet, handler("get-v1"))
+	g.AddVersionedHandler("2", http.MethodGet, handler("get-v2"))
+	g.AddVersionedHandler("2", http.MethodDelete, handler("delete-v2"))
+	g.AddVersionedHandler("2", http.MethodDelete, handler("delete-v2"))
+	g.AddVersionedHandler("", http.MethodGet, handler("empty"))
+
+	if err := g.Validate(); err == nil || len(err.(*ValidationError).Errors) != 2 {
+		t.Logf("expected 2 validation errors, got %v", err)
+		t.Fail()
+	}
+
+	mux := NewServeMux().SetVersioning(NewVersioning(VersionFromHeader("X-API-Version"), VersionFromQuery("v"), VersionFromAccept("version")).
+		SetDefault("2").
+		Deprecate("1", sunset))
+	mux.Handle("/items", g)
+
+	tests := []struct {
+		method  string
+		url     string
+		header  [2]string
+		status  int
+		body    string
+		version string
+	}{
+		{method: http.MethodGet, url: "/items", status: http.StatusOK, body: "get-v2", version: "2"},
+		{method: http.MethodGet, url: "/items", header: [2]string{"X-API-Version", "1"}, status: http.StatusOK, body: "get-v1", version: "1"},
+		{method: http.MethodGet, url: "/items?v=1", status: http.StatusOK, body: "get-v1", version: "1"},
+		{method: http.MethodGet, url: "/items", header: [2]string{"Accept", "application/json; version=1"}, status: http.StatusOK, body: "get-v1", version: "1"},
+		{method: http.MethodDelete, url: "/items?v=1", status: http.StatusMethodNotAllowed, version: "1"},
+		{method: http.MethodDelete, url: "/items", status: http.StatusOK, body: "delete-v2", version: "2"},
+		{method: http.MethodGet, url: "/items?v=3", status: http.StatusBadRequest, body: "unsupported API version 3", version: "3"},
+		{method: http.MethodDelete, url: "/items?v=3", status: http.StatusBadRequest, body: "unsupported API version 3", version: "3"},
+	}
+
+	h := mux.Make()
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.url, nil)
+		if tt.header[0] != "" {
+			req.Header.Set(tt.header[0], tt.header[1])
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		if res.Code != tt.status || !strings.Contains(res.Body.String(), tt.body) || version != tt.version {
+			t.Logf("%s %s: unexpected response %d %s (version %q)", tt.method, tt.url, res.Code, res.Body.String(), version)
+			t.Fail()
+		}
+
+		deprecated := res.Header().Get("Deprecation") == "true" && res.Header().Get("Sunset") == "Tue, 01 Jan 2030 00:00:00 GMT"
+		if deprecated != (tt.version == "1") {
+			t.Logf("%s %s: unexpected deprecation headers %v", tt.method, tt.url, res.Header())
+			t.Fail()
+		}
+	}
+
+	if r := mux.Routes(); strings.Join(r[0].Methods, ",") != "DELETE,GET" || strings.Join(r[0].Versions, ",") != "1,2" {
+		t.Logf("unexpected route: %+v", r[0])
+		t.Fail()
+	}
+
+	t.Run("base fallback", func(t *testing.T) {
+		g := New().SetVersioning(NewVersioning(VersionFromHeader("X-API-Version")).SetDefault("1"))
+		g.Get(func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return Response("base"), nil
+		})
+		g.AddVersionedHandler("2", http.MethodGet, func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+			return Response("v2"), nil
+		})
+		h := g.Make()
+
+		tests := []struct {
+			method  string
+			version string
+			status  int
+			body    string
+		}{
+			{method: http.MethodGet, status: http.StatusOK, body: "base"},
+			{method: http.MethodGet, version: "2", status: http.StatusOK, body: "v2"},
+			{method: http.MethodGet, version: "1", status: http.StatusBadRequest},
+			{method: http.MethodGet, version: "garbage", status: http.StatusBadRequest},
+			{method: http.MethodPost, status: http.StatusMethodNotAllowed},
+			{method: http.MethodPost, version: "2", status: http.StatusMethodNotAllowed},
+		}
+
+		for _, tt := range tests {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.version != "" {
+				req.Header.Set("X-API-Version", tt.version)
+			}
+
+			res := httptest.NewRecorder()
+			h(res, req)
+
+			if res.Code != tt.status || (tt.body != "" && res.Body.String() != tt.body) {
+				t.Logf("%s version %q: unexpected response %d %s", tt.method, tt.version, res.Code, res.Body.String())
+				t.Fail()
+			}
+		}
+	})
+}
+
+func TestVersionFromPath(t *testing.T) {
+	Config.Reset()
+	defer Config.Reset()
+	Config.SetGlobalLogger(nil)
+
+	g := New().SetVersioning(NewVersioning(VersionFromPath("version")))
+	g.AddVersionedHandler("v1", http.MethodGet, func(w http.ResponseWriter, req *http.Request) (*HandlerResponse, error) {
+		return Response("v1"), nil
+	})
+
+	mux := NewServeMux()
+	mux.Handle("/{version}/items", g)
+
+	res := httptest.NewRecorder()
+	mux.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/v1/items", nil))
+
+	if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), "v1") {
+		t.Logf("unexpected response %d %s", res.Code, res.Body.String())
+		t.Fail()
+	}
+
+	res = httptest.NewRecorder()
+	mux.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/v2/items", nil))
+
+	if res.Code != http.StatusBadRequest {
+		t.Logf("expected 400, got %d", res.Code)
+		t.Fail()
+	}
+}